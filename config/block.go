@@ -20,7 +20,8 @@ type Block struct {
 	config    *Config
 	container entryContainer
 	rawBlock  *rawparser.BlockDirective
-	rawDumper *rawdumper.RawDumper
+	// includedBy is the include directive the block was found through
+	includedBy *Directive
 }
 
 func (b *Block) GetName() string {
@@ -31,15 +32,21 @@ func (b *Block) GetParameters() []string {
 	return b.rawBlock.GetParametersExpressions()
 }
 
+func (b *Block) GetPosition() Position {
+	return newPosition(b.rawBlock.Pos)
+}
+
 func (b *Block) SetParameters(parameters []string) {
-	b.rawBlock.SetParameters(parameters)
+	b.config.edit(EditSetParameters, b.FilePath, b.container, getBlockContextName(b.rawBlock), func() {
+		b.rawBlock.SetParameters(parameters)
+	})
 }
 
 func (b *Block) FindDirectives(directiveName string) []Directive {
 	var directives []Directive
 
 	for _, entry := range b.rawBlock.GetEntries() {
-		directives = append(directives, b.config.findDirectivesRecursively(directiveName, b.rawBlock, entry, true)...)
+		directives = append(directives, b.config.findDirectivesRecursively(directiveName, b.FilePath, b.rawBlock, entry, true, includeGuard{}, b.includedBy)...)
 	}
 
 	return directives
@@ -49,22 +56,45 @@ func (b *Block) FindBlocks(blockName string) []Block {
 	var blocks []Block
 
 	for _, entry := range b.rawBlock.GetEntries() {
-		blocks = append(blocks, b.config.findBlocksRecursively(blockName, b.FilePath, b.rawBlock, entry, true)...)
+		blocks = append(blocks, b.config.findBlocksRecursively(blockName, b.FilePath, b.rawBlock, entry, true, includeGuard{}, b.includedBy)...)
 	}
 
 	return blocks
 }
 
-func (b *Block) AddDirective(directive Directive, begining bool, endWithNewLine bool) {
-	addDirective(b.rawBlock, directive, begining, endWithNewLine)
+// AddDirective adds the directive to the block and returns it attached to the block.
+// Further changes of the directive have to be made through the returned one, so that the file is marked as modified.
+func (b *Block) AddDirective(directive Directive, begining bool, endWithNewLine bool) Directive {
+	b.config.edit(EditAddDirective, b.FilePath, b.rawBlock, directive.GetName(), func() {
+		addDirective(b.rawBlock, directive, begining, endWithNewLine)
+	})
+
+	return Directive{
+		rawDirective: directive.rawDirective,
+		container:    b.rawBlock,
+		config:       b.config,
+		filePath:     b.FilePath,
+		includedBy:   b.includedBy,
+	}
+}
+
+// AddFragment copies blocks, directives and comments of a fragment created with ParseString into the block
+func (b *Block) AddFragment(fragment *ConfigFile, begining bool) {
+	b.config.edit(EditAddFragment, b.FilePath, b.rawBlock, "", func() {
+		addEntries(b.rawBlock, fragment.configFile.GetEntries(), begining)
+	})
 }
 
 func (b *Block) DeleteDirective(directive Directive) {
-	deleteDirective(b.rawBlock, directive)
+	b.config.edit(EditDeleteDirective, b.FilePath, b.rawBlock, directive.GetName(), func() {
+		deleteDirective(b.rawBlock, directive)
+	})
 }
 
 func (b *Block) DeleteDirectiveByName(directiveName string) {
-	deleteDirectiveByName(b.rawBlock, directiveName)
+	b.config.edit(EditDeleteDirective, b.FilePath, b.rawBlock, directiveName, func() {
+		deleteDirectiveByName(b.rawBlock, directiveName)
+	})
 }
 
 func (b *Block) FindComments() []Comment {
@@ -156,13 +186,14 @@ func (b *Block) SetComments(comments []string) {
 		nIndex = rIndex
 	}
 
-	if nIndex != index {
-		entries = slices.Delete(entries, nIndex, index)
-	}
-
-	entries = slices.Insert(entries, nIndex, pEntries...)
+	b.config.edit(EditSetComments, b.FilePath, b.container, getBlockContextName(b.rawBlock), func() {
+		if nIndex != index {
+			entries = slices.Delete(entries, nIndex, index)
+		}
 
-	setEntries(b.container, entries)
+		entries = slices.Insert(entries, nIndex, pEntries...)
+		setEntries(b.container, entries)
+	})
 }
 
 func (b *Block) Dump() string {
@@ -170,7 +201,10 @@ func (b *Block) Dump() string {
 		BlockDirective: b.rawBlock,
 	}
 
-	return b.rawDumper.DumpEntry(&entry)
+	// a dumper keeps the nesting level, so it is not shared between calls
+	dumper := &rawdumper.RawDumper{}
+
+	return dumper.DumpEntry(&entry)
 }
 
 func (b *Block) findInlineComment(blockDirective *rawparser.BlockDirective) *Comment {
@@ -204,7 +238,16 @@ func (b *Block) createComment(rawComment *rawparser.Comment, position CommentPos
 }
 
 func (b *Block) addBlock(name string, parameters []string, begining bool) Block {
-	return newBlock(b.rawBlock, b.config, name, parameters, begining)
+	block := newBlock(b.rawBlock, b.config, b.FilePath, name, parameters, begining)
+	block.includedBy = b.includedBy
+
+	return block
+}
+
+func (b *Block) deleteBlock(block Block) {
+	b.config.edit(EditDeleteBlock, b.FilePath, b.rawBlock, getBlockContextName(block.rawBlock), func() {
+		deleteBlock(b.rawBlock, block)
+	})
 }
 
 func (b *Block) setContainer(container entryContainer) {
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"github.com/r2dtools/gonginxconf/internal/rawdumper"
 	"github.com/r2dtools/gonginxconf/internal/rawparser"
 	"golang.org/x/exp/slices"
 )
@@ -92,7 +91,14 @@ func findUpstreamBlocksByName(finder upstreamBlockFinder, upstreamName string) [
 	return upstreamBlocks
 }
 
-func newBlock(container entryContainer, config *Config, name string, parameters []string, begining bool) Block {
+func newBlock(
+	container entryContainer,
+	config *Config,
+	filePath string,
+	name string,
+	parameters []string,
+	begining bool,
+) Block {
 	rawBlock := &rawparser.BlockDirective{
 		Identifier: name,
 		Content:    &rawparser.BlockContent{},
@@ -100,10 +106,10 @@ func newBlock(container entryContainer, config *Config, name string, parameters
 	rawBlock.SetParameters(parameters)
 
 	block := Block{
+		FilePath:  filePath,
 		config:    config,
 		container: container,
 		rawBlock:  rawBlock,
-		rawDumper: &rawdumper.RawDumper{},
 	}
 
 	entries := container.GetEntries()
@@ -145,16 +151,19 @@ func newBlock(container entryContainer, config *Config, name string, parameters
 		EndNewLines:    []string{"\n\n"},
 	}
 
-	if indexToInsert == -1 {
-		entries = append(entries, entry)
-	} else {
-		if indexToInsert == 0 {
-			entry.StartNewLines = []string{"\n"}
-		}
-		entries = slices.Insert(entries, indexToInsert, entry)
+	if indexToInsert == 0 {
+		entry.StartNewLines = []string{"\n"}
 	}
 
-	setEntries(container, entries)
+	config.edit(EditAddBlock, filePath, container, getBlockContextName(rawBlock), func() {
+		if indexToInsert == -1 {
+			entries = append(entries, entry)
+		} else {
+			entries = slices.Insert(entries, indexToInsert, entry)
+		}
+
+		setEntries(container, entries)
+	})
 
 	return block
 }
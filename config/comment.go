@@ -15,6 +15,10 @@ type Comment struct {
 	Position   CommentPosition
 }
 
+func (c *Comment) GetPosition() Position {
+	return newPosition(c.rawComment.Pos)
+}
+
 func newComment(content string) Comment {
 	rawComment := &rawparser.Comment{
 		Value: "# " + content,
@@ -0,0 +1,22 @@
+package config
+
+// View calls the function holding the read lock of the configuration. Several views can run
+// concurrently, so the function must only find, read and dump objects and call Diff.
+// Objects found in the function can be kept and used in later calls of View and Update.
+func (c *Config) View(fn func(config *Config) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return fn(c)
+}
+
+// Update calls the function holding the write lock of the configuration. Updates are serialized
+// and never run concurrently with views, so the function can change and dump the configuration,
+// Reload and Rebase it. Other methods do not lock, so a configuration that is used by several goroutines
+// must be accessed only inside View and Update. Watcher never changes the configuration it watches.
+func (c *Config) Update(fn func(config *Config) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return fn(c)
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// run with -race to detect unsynchronized access
+func TestConcurrentViewAndUpdate(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+
+	for reader := 0; reader < 4; reader++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for iteration := 0; iteration < 50; iteration++ {
+				err := config.View(func(config *Config) error {
+					for _, serverBlock := range config.FindServerBlocks() {
+						serverBlock.GetServerNames()
+						serverBlock.Dump()
+
+						for _, locationBlock := range serverBlock.FindLocationBlocks() {
+							locationBlock.GetLocationMatch()
+						}
+					}
+
+					for _, directive := range config.FindDirectives("fastcgi_param") {
+						directive.GetValues()
+					}
+
+					_, err := config.Diff()
+
+					return err
+				})
+				assert.Nil(t, err)
+			}
+		}()
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for iteration := 0; iteration < 50; iteration++ {
+			err := config.Update(func(config *Config) error {
+				serverBlocks := config.FindServerBlocksByServerName("example.com")
+				serverBlocks[0].AddDirective(NewDirective("listen", []string{fmt.Sprint(8000 + iteration)}), false, false)
+
+				directives := config.FindDirectives("fastcgi_param")
+				directives[0].SetValues([]string{"SCRIPT_FILENAME", fmt.Sprintf("/var/www/%d", iteration)})
+
+				if err := config.Dump(); err != nil {
+					return err
+				}
+
+				_, err := config.Poll()
+
+				return err
+			})
+			assert.Nil(t, err)
+		}
+	}()
+
+	wg.Wait()
+
+	err = config.View(func(config *Config) error {
+		assert.Len(t, config.FindDirectives("listen"), 50)
+		assert.Empty(t, config.ModifiedFiles())
+
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/snippets/fastcgi.conf"].Data), "/var/www/49")
+}
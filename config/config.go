@@ -3,45 +3,119 @@ package config
 import (
 	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
-	"github.com/r2dtools/gonginxconf/internal/rawdumper"
 	"github.com/r2dtools/gonginxconf/internal/rawparser"
-	"github.com/unknwon/com"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 var repeatableDirectives = []string{"server_name", "listen", "include", "rewrite", "add_header"}
 
 var ErrInvalidDirective = errors.New("entry is not a directive")
+var ErrIncludeCycle = errors.New("include cycle detected")
+var ErrConfigFileNotFound = errors.New("config file not found")
+
+// AmbiguousConfigFileError is returned when a base name matches several files
+type AmbiguousConfigFileError struct {
+	Name      string
+	FilePaths []string
+}
+
+func (e *AmbiguousConfigFileError) Error() string {
+	return fmt.Sprintf("config file name %s is ambiguous: %s", e.Name, strings.Join(e.FilePaths, ", "))
+}
 
 type Config struct {
-	rawParser   *rawparser.RawParser
-	rawDumper   *rawdumper.RawDumper
-	parsedFiles map[string]*rawparser.Config
-	serverRoot  string
-	configRoot  string
-	quiteMode   bool
+	mu            sync.RWMutex
+	rawParser     *rawparser.RawParser
+	fileSystem    fileSystem
+	parsedFiles   map[string]*rawparser.Config
+	serverRoot    string
+	configRoot    string
+	quiteMode     bool
+	includeEdges  []IncludeEdge
+	modifiedFiles map[string]bool
+	newFiles      map[string]bool
+	removedFiles  map[string]bool
+	// newSymlinks maps links created by EnableSite to their targets
+	newSymlinks     map[string]string
+	removedSymlinks map[string]bool
+	fileStates      map[string]fileState
+	warnings        []error
+	// extraFiles are loaded with ParseFile in addition to the main configuration file
+	extraFiles []string
+	reload     *reloadState
+	// renamedFiles maps new paths of renamed files to their paths on disk
+	renamedFiles map[string]string
+	// movedFiles maps old paths of renamed files to new ones
+	movedFiles  map[string]string
+	edits       []Edit
+	undoneEdits []Edit
+	// historyLimit is the number of edits kept for Undo, the edit log is disabled if it is 0
+	historyLimit int
+	// transactions are not committed or rolled back yet
+	transactions []*Transaction
 }
 
+// GetConfigFile returns the file by its absolute path, path relative to the server root or base name.
+// Nil is returned if the file is not found or the base name matches several files, see FindConfigFile.
 func (c *Config) GetConfigFile(configFileName string) *ConfigFile {
-	for configFilePath, config := range c.parsedFiles {
-		pConfigFileName := filepath.Base(configFilePath)
+	configFile, err := c.FindConfigFile(configFileName)
 
-		if configFileName == pConfigFileName {
-			return &ConfigFile{
-				FilePath:   configFilePath,
-				configFile: config,
-				config:     c,
-			}
+	if err != nil {
+		return nil
+	}
+
+	return configFile
+}
+
+// FindConfigFile looks the file up by its absolute path, path relative to the server root or base name.
+// AmbiguousConfigFileError is returned if the base name matches several files.
+func (c *Config) FindConfigFile(configFileName string) (*ConfigFile, error) {
+	if configFileName == "" {
+		return nil, fmt.Errorf("%w: empty file name", ErrConfigFileNotFound)
+	}
+
+	if filePath := c.getAbsPath(configFileName); c.parsedFiles[filePath] != nil {
+		return c.getConfigFile(filePath), nil
+	}
+
+	var filePaths []string
+
+	for _, configFile := range c.ConfigFiles() {
+		if filepath.Base(configFile.FilePath) == configFileName {
+			filePaths = append(filePaths, configFile.FilePath)
 		}
 	}
 
-	return nil
+	switch len(filePaths) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrConfigFileNotFound, configFileName)
+	case 1:
+		return c.getConfigFile(filePaths[0]), nil
+	}
+
+	return nil, &AmbiguousConfigFileError{Name: configFileName, FilePaths: filePaths}
+}
+
+// ConfigFiles returns all files of the configuration sorted by path
+func (c *Config) ConfigFiles() []*ConfigFile {
+	filePaths := maps.Keys(c.parsedFiles)
+	sort.Strings(filePaths)
+
+	configFiles := make([]*ConfigFile, 0, len(filePaths))
+
+	for _, filePath := range filePaths {
+		configFiles = append(configFiles, c.getConfigFile(filePath))
+	}
+
+	return configFiles
 }
 
 func (c *Config) FindHttpBlocks() []HttpBlock {
@@ -64,47 +138,62 @@ func (c *Config) FindUpstreamBlocksByName(upstreamName string) []UpstreamBlock {
 	return findUpstreamBlocksByName(c, upstreamName)
 }
 
+// FindDirectives returns directives in the order nginx loads them: included files are searched
+// at the place of the include directive. A directive of a file included several times is returned once
+// at its first place, FindDirectivesInContext returns it at every place.
 func (c *Config) FindDirectives(directiveName string) []Directive {
 	var directives []Directive
+	found := make(map[*rawparser.Directive]bool)
+
+	for _, directive := range c.findDirectivesAtEveryInclude(directiveName) {
+		if !found[directive.rawDirective] {
+			found[directive.rawDirective] = true
+			directives = append(directives, directive)
+		}
+	}
 
-	keys := maps.Keys(c.parsedFiles)
-	sort.Strings(keys)
+	return directives
+}
 
-	for _, key := range keys {
-		tree, ok := c.parsedFiles[key]
+// FindBlocks returns blocks in the order nginx loads them, see FindDirectives
+func (c *Config) FindBlocks(blockName string) []Block {
+	var blocks []Block
+	found := make(map[*rawparser.BlockDirective]bool)
 
-		if !ok {
-			continue
+	for _, block := range c.findBlocksAtEveryInclude(blockName) {
+		if !found[block.rawBlock] {
+			found[block.rawBlock] = true
+			blocks = append(blocks, block)
 		}
+	}
 
+	return blocks
+}
+
+// findDirectivesAtEveryInclude searches a file included several times at every place it is included
+func (c *Config) findDirectivesAtEveryInclude(directiveName string) []Directive {
+	var directives []Directive
+
+	c.walkFiles(func(filePath string, tree *rawparser.Config, guard includeGuard) {
 		for _, entry := range tree.GetEntries() {
 			directives = append(
 				directives,
-				c.findDirectivesRecursively(directiveName, tree, entry, false)...,
+				c.findDirectivesRecursively(directiveName, filePath, tree, entry, true, guard, nil)...,
 			)
 		}
-	}
+	})
 
 	return directives
 }
 
-func (c *Config) FindBlocks(blockName string) []Block {
+func (c *Config) findBlocksAtEveryInclude(blockName string) []Block {
 	var blocks []Block
 
-	keys := maps.Keys(c.parsedFiles)
-	sort.Strings(keys)
-
-	for _, key := range keys {
-		tree, ok := c.parsedFiles[key]
-
-		if !ok {
-			continue
-		}
-
+	c.walkFiles(func(filePath string, tree *rawparser.Config, guard includeGuard) {
 		for _, entry := range tree.Entries {
-			blocks = append(blocks, c.findBlocksRecursively(blockName, key, tree, entry, false)...)
+			blocks = append(blocks, c.findBlocksRecursively(blockName, filePath, tree, entry, true, guard, nil)...)
 		}
-	}
+	})
 
 	return blocks
 }
@@ -113,170 +202,300 @@ func (c *Config) FindLocationBlocks() []LocationBlock {
 	return findLocationBlocks(c)
 }
 
+// ModifiedFiles returns files changed in memory since they were parsed or dumped last time
+func (c *Config) ModifiedFiles() []string {
+	modifiedFiles := maps.Keys(c.modifiedFiles)
+	sort.Strings(modifiedFiles)
+
+	return modifiedFiles
+}
+
+// Dump writes modified files only, see ModifiedFiles
 func (c *Config) Dump() error {
-	for filePath, config := range c.parsedFiles {
-		content, err := c.rawDumper.Dump(config)
+	return c.DumpWithOptions(DumpOptions{})
+}
 
-		if err != nil {
-			return err
-		}
+// AddConfigFile creates an empty file that is written on Dump. Relative paths are resolved
+// against the current working directory, for configurations parsed from fs.FS against its root.
+func (c *Config) AddConfigFile(filePath string) (*ConfigFile, error) {
+	filePath, err := c.fileSystem.Abs(filePath)
 
-		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return err
-		}
+	if _, ok := c.parsedFiles[filePath]; ok {
+		return nil, fmt.Errorf("file %s already exists", filePath)
+	}
 
-		defer file.Close()
+	if _, err := c.fileSystem.Stat(filePath); !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("file %s already exists", filePath)
+	}
 
-		_, err = file.WriteString(content)
+	configFile := ConfigFile{
+		FilePath:   filePath,
+		configFile: &rawparser.Config{},
+		config:     c,
+	}
 
-		if err != nil {
-			return err
-		}
+	if c.parsedFiles == nil {
+		c.parsedFiles = make(map[string]*rawparser.Config)
 	}
 
-	return nil
+	if c.newFiles == nil {
+		c.newFiles = make(map[string]bool)
+	}
+
+	c.parsedFiles[filePath] = configFile.configFile
+	c.newFiles[filePath] = true
+	delete(c.movedFiles, filePath)
+	c.markModified(filePath)
+
+	return &configFile, nil
 }
 
-func (c *Config) AddConfigFile(filePath string) (*ConfigFile, error) {
-	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
-		configFile := ConfigFile{
-			FilePath:   filePath,
-			configFile: &rawparser.Config{},
-			config:     c,
-		}
+// AddIncludedConfigFile creates an empty file like AddConfigFile and includes it into the parent block.
+// The include directive refers to the file relative to the server root if the file is located in it.
+func (c *Config) AddIncludedConfigFile(filePath string, parent *Block, begining bool) (*ConfigFile, error) {
+	configFile, err := c.AddConfigFile(filePath)
 
-		return &configFile, nil
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("file %s already exists", filePath)
+	directive := parent.AddDirective(NewDirective("include", []string{c.getIncludePath("", configFile.FilePath)}), begining, false)
+
+	c.includeEdges = append(c.includeEdges, IncludeEdge{
+		From:      parent.FilePath,
+		To:        configFile.FilePath,
+		Directive: directive,
+	})
+
+	return configFile, nil
 }
 
 func (c *Config) ParseFile(filePath string) error {
-	return c.parseRecursively(filePath)
+	if _, err := c.parseRecursively(filePath, nil); err != nil {
+		return err
+	}
+
+	c.extraFiles = append(c.extraFiles, filePath)
+
+	return nil
+}
+
+// Warnings returns errors of included files that were skipped in the quite mode
+func (c *Config) Warnings() []error {
+	return slices.Clone(c.warnings)
 }
 
 func (c *Config) parse() error {
 	c.parsedFiles = make(map[string]*rawparser.Config)
-
-	return c.parseRecursively(c.configRoot)
+	c.modifiedFiles = make(map[string]bool)
+	c.newFiles = make(map[string]bool)
+	c.removedFiles = make(map[string]bool)
+	c.renamedFiles = make(map[string]string)
+	c.movedFiles = make(map[string]string)
+	c.newSymlinks = make(map[string]string)
+	c.removedSymlinks = make(map[string]bool)
+	c.fileStates = make(map[string]fileState)
+	c.includeEdges = nil
+	c.warnings = nil
+	c.extraFiles = nil
+	c.edits = nil
+	c.undoneEdits = nil
+
+	_, err := c.parseRecursively(c.configRoot, nil)
+
+	return err
 }
 
-func (c *Config) parseRecursively(configFilePath string) error {
+// parseRecursively parses files matching the path together with all files they include
+// and returns the matched files that were parsed successfully.
+func (c *Config) parseRecursively(configFilePath string, includeChain []string) ([]string, error) {
 	configFilePathAbs := c.getAbsPath(configFilePath)
-	trees, err := c.parseFilesByPath(configFilePathAbs, false)
+	files, err := c.findFilesByPath(configFilePathAbs)
 
 	if err != nil {
-		return err
+		return nil, c.handleParseError(includeChain, configFilePathAbs, err)
 	}
 
-	for _, tree := range trees {
-		for _, entry := range tree.Entries {
-			identifier := strings.ToLower(entry.GetIdentifier())
-			// Parse the top-level included file
-			if identifier == "include" {
-				if entry.Directive == nil {
-					return ErrInvalidDirective
-				}
+	var parsedFiles []string
+
+	for _, file := range files {
+		if slices.Contains(includeChain, file) {
+			if err := c.handleParseError(includeChain, file, ErrIncludeCycle); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if _, ok := c.parsedFiles[file]; !ok {
+			tree, err := c.loadFile(file)
 
-				includeFile := entry.Directive.GetFirstValueStr()
-				if includeFile != "" {
-					c.parseRecursively(includeFile)
+			if err != nil {
+				if err := c.handleParseError(includeChain, file, err); err != nil {
+					return nil, err
 				}
+
 				continue
 			}
 
-			// Look for includes in the top-level 'http'/'server' context
-			if identifier == "http" || identifier == "server" {
-				if entry.BlockDirective == nil {
-					continue
-				}
+			c.parsedFiles[file] = tree
+			fileIncludeChain := append(slices.Clone(includeChain), file)
 
-				for _, subEntry := range entry.BlockDirective.GetEntries() {
-					subIdentifier := strings.ToLower(subEntry.GetIdentifier())
-					if subIdentifier == "include" {
-						if subEntry.Directive == nil {
-							return ErrInvalidDirective
-						}
-
-						includeFile := subEntry.Directive.GetFirstValueStr()
-						if includeFile != "" {
-							c.parseRecursively(includeFile)
-						}
-						continue
-					}
-
-					// Look for includes in a 'server' context within an 'http' context
-					if identifier == "http" && subIdentifier == "server" {
-						if subEntry.BlockDirective == nil {
-							continue
-						}
-
-						for _, serverEntry := range subEntry.BlockDirective.GetEntries() {
-							if strings.ToLower(serverEntry.GetIdentifier()) == "include" {
-								if serverEntry.Directive == nil {
-									return ErrInvalidDirective
-								}
-
-								includeFile := serverEntry.Directive.GetFirstValueStr()
-								if includeFile != "" {
-									c.parseRecursively(includeFile)
-								}
-							}
-						}
-					}
-				}
+			if err := c.parseIncludes(file, tree, fileIncludeChain); err != nil {
+				return nil, err
 			}
 		}
+
+		parsedFiles = append(parsedFiles, file)
 	}
 
-	return nil
+	return parsedFiles, nil
 }
 
-func (c *Config) parseFilesByPath(filePath string, override bool) ([]*rawparser.Config, error) {
-	var files []string
+// parseIncludes looks for include directives in any context at any depth
+// and parses the included files in the order they appear.
+func (c *Config) parseIncludes(filePath string, container entryContainer, includeChain []string) error {
+	for _, entry := range container.GetEntries() {
+		if strings.ToLower(entry.GetIdentifier()) == "include" {
+			if entry.Directive == nil {
+				return ErrInvalidDirective
+			}
 
-	stat, err := os.Stat(filePath)
+			includeFile := entry.Directive.GetFirstValueStr()
 
-	if err == nil && stat.Mode().IsRegular() {
-		files = []string{filePath}
-	} else {
-		files, err = filepath.Glob(filePath)
+			if includeFile == "" {
+				continue
+			}
 
-		if err != nil {
-			return nil, err
-		}
-	}
+			includedFiles, err := c.parseRecursively(includeFile, includeChain)
 
-	var trees []*rawparser.Config
+			if err != nil {
+				return err
+			}
+
+			for _, includedFile := range includedFiles {
+				c.includeEdges = append(c.includeEdges, IncludeEdge{
+					From:     filePath,
+					To:       includedFile,
+					Position: newPosition(entry.Directive.Pos),
+					Directive: Directive{
+						rawDirective: entry.Directive,
+						container:    container,
+						config:       c,
+						filePath:     filePath,
+					},
+				})
+			}
 
-	for _, file := range files {
-		if _, ok := c.parsedFiles[file]; ok && !override {
 			continue
 		}
 
-		content, err := os.ReadFile(file)
-
-		if err != nil {
-			if c.quiteMode {
-				continue
+		if entry.BlockDirective != nil {
+			if err := c.parseIncludes(filePath, entry.BlockDirective, includeChain); err != nil {
+				return err
 			}
-
-			return nil, err
 		}
+	}
+
+	return nil
+}
+
+func (c *Config) findFilesByPath(filePath string) ([]string, error) {
+	stat, err := c.fileSystem.Stat(filePath)
+
+	if err == nil && stat.Mode().IsRegular() {
+		return []string{filePath}, nil
+	}
 
-		config, err := c.rawParser.Parse(string(content))
+	return c.fileSystem.Glob(filePath)
+}
+
+// findIncludedFiles matches an include path against parsed files including files that
+// were created, renamed or enabled in memory and do not exist on disk yet
+func (c *Config) findIncludedFiles(includePath string) ([]string, error) {
+	includePath = c.getAbsPath(includePath)
+
+	var files []string
+
+	for filePath := range c.parsedFiles {
+		matched, err := filepath.Match(includePath, filePath)
 
 		if err != nil {
 			return nil, err
 		}
 
-		c.parsedFiles[file] = config
-		trees = append(trees, config)
+		if matched {
+			files = append(files, filePath)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func (c *Config) parseFile(filePath string) (*rawparser.Config, error) {
+	content, err := c.fileSystem.ReadFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := c.rawParser.Parse(filePath, string(content))
+
+	if err != nil {
+		return nil, newParseError(filePath, string(content), err)
+	}
+
+	c.recordFileState(filePath, content)
+
+	return config, nil
+}
+
+// handleParseError attaches the include chain to errors of included files.
+// In the quite mode such errors are collected as warnings and parsing continues.
+func (c *Config) handleParseError(includeChain []string, filePath string, err error) error {
+	if len(includeChain) == 0 {
+		return err
+	}
+
+	err = &IncludeError{
+		Chain:      append(slices.Clone(includeChain), filePath),
+		Err:        err,
+		serverRoot: c.serverRoot,
+	}
+
+	if c.quiteMode {
+		c.warnings = append(c.warnings, err)
+
+		return nil
+	}
+
+	return err
+}
+
+func (c *Config) markModified(filePath string) {
+	if c == nil || filePath == "" {
+		return
+	}
+
+	if c.modifiedFiles == nil {
+		c.modifiedFiles = make(map[string]bool)
 	}
 
-	return trees, nil
+	c.modifiedFiles[c.getCurrentPath(filePath)] = true
+}
+
+func (c *Config) getConfigFile(filePath string) *ConfigFile {
+	return &ConfigFile{
+		FilePath:   filePath,
+		configFile: c.parsedFiles[filePath],
+		config:     c,
+	}
 }
 
 func (c *Config) getAbsPath(path string) string {
@@ -289,9 +508,12 @@ func (c *Config) getAbsPath(path string) string {
 
 func (c *Config) findDirectivesRecursively(
 	directiveName string,
+	path string,
 	container entryContainer,
 	entry *rawparser.Entry,
 	withInclude bool,
+	guard includeGuard,
+	includedBy *Directive,
 ) []Directive {
 	var directives []Directive
 	directive := entry.Directive
@@ -301,8 +523,14 @@ func (c *Config) findDirectivesRecursively(
 		identifier := directive.Identifier
 
 		if withInclude && identifier == "include" {
-			include := c.getAbsPath(directive.GetFirstValueStr())
-			includeFiles, err := filepath.Glob(include)
+			includeFiles, err := c.findIncludedFiles(directive.GetFirstValueStr())
+			include := &Directive{
+				rawDirective: directive,
+				container:    container,
+				config:       c,
+				filePath:     path,
+				includedBy:   includedBy,
+			}
 
 			if err != nil {
 				return directives
@@ -315,10 +543,16 @@ func (c *Config) findDirectivesRecursively(
 					continue
 				}
 
+				includeGuard, ok := guard.enter(includePath)
+
+				if !ok {
+					continue
+				}
+
 				for _, entry := range includeConfig.GetEntries() {
 					directives = append(
 						directives,
-						c.findDirectivesRecursively(directiveName, includeConfig, entry, withInclude)...,
+						c.findDirectivesRecursively(directiveName, includePath, includeConfig, entry, withInclude, includeGuard, include)...,
 					)
 				}
 			}
@@ -328,6 +562,9 @@ func (c *Config) findDirectivesRecursively(
 			directives = append(directives, Directive{
 				rawDirective: directive,
 				container:    container,
+				config:       c,
+				filePath:     path,
+				includedBy:   includedBy,
 			})
 
 			return directives
@@ -338,7 +575,7 @@ func (c *Config) findDirectivesRecursively(
 		for _, bEntry := range blockDirective.GetEntries() {
 			directives = append(
 				directives,
-				c.findDirectivesRecursively(directiveName, blockDirective, bEntry, withInclude)...,
+				c.findDirectivesRecursively(directiveName, path, blockDirective, bEntry, withInclude, guard, includedBy)...,
 			)
 		}
 
@@ -354,14 +591,22 @@ func (c *Config) findBlocksRecursively(
 	container entryContainer,
 	entry *rawparser.Entry,
 	withInclude bool,
+	guard includeGuard,
+	includedBy *Directive,
 ) []Block {
 	var blocks []Block
 	directive := entry.Directive
 	blockDirective := entry.BlockDirective
 
 	if withInclude && directive != nil && directive.Identifier == "include" {
-		include := c.getAbsPath(directive.GetFirstValueStr())
-		includeFiles, err := filepath.Glob(include)
+		includeFiles, err := c.findIncludedFiles(directive.GetFirstValueStr())
+		include := &Directive{
+			rawDirective: directive,
+			container:    container,
+			config:       c,
+			filePath:     path,
+			includedBy:   includedBy,
+		}
 
 		if err != nil {
 			return blocks
@@ -374,10 +619,16 @@ func (c *Config) findBlocksRecursively(
 				continue
 			}
 
+			includeGuard, ok := guard.enter(includePath)
+
+			if !ok {
+				continue
+			}
+
 			for _, entry := range includeConfig.Entries {
 				blocks = append(
 					blocks,
-					c.findBlocksRecursively(blockName, includePath, includeConfig, entry, withInclude)...,
+					c.findBlocksRecursively(blockName, includePath, includeConfig, entry, withInclude, includeGuard, include)...,
 				)
 			}
 		}
@@ -390,18 +641,18 @@ func (c *Config) findBlocksRecursively(
 
 		if identifier == blockName {
 			blocks = append(blocks, Block{
-				FilePath:  path,
-				config:    c,
-				container: container,
-				rawBlock:  blockDirective,
-				rawDumper: &rawdumper.RawDumper{},
+				FilePath:   path,
+				config:     c,
+				container:  container,
+				rawBlock:   blockDirective,
+				includedBy: includedBy,
 			})
 		} else {
 			// blocks can be nested
 			for _, httpBlockEntry := range blockDirective.GetEntries() {
 				blocks = append(
 					blocks,
-					c.findBlocksRecursively(blockName, path, blockDirective, httpBlockEntry, withInclude)...,
+					c.findBlocksRecursively(blockName, path, blockDirective, httpBlockEntry, withInclude, guard, includedBy)...,
 				)
 			}
 		}
@@ -423,6 +674,17 @@ func GetConfig(serverRootPath, configFilePath string, quiteMode bool) (*Config,
 		}
 	}
 
+	return getConfig(osFileSystem{}, serverRootPath, configFilePath, quiteMode)
+}
+
+// GetConfigFromFS parses the configuration stored in fsys, e.g. embed.FS or a container image layer.
+// The root of fsys is treated as "/", so absolute paths in include directives are resolved inside fsys.
+// Dump requires fsys to implement WriteFileFS.
+func GetConfigFromFS(fsys fs.FS, serverRootPath, configFilePath string, quiteMode bool) (*Config, error) {
+	return getConfig(ioFileSystem{fsys: fsys}, path.Join("/", serverRootPath), configFilePath, quiteMode)
+}
+
+func getConfig(fileSystem fileSystem, serverRootPath, configFilePath string, quiteMode bool) (*Config, error) {
 	if configFilePath == "" {
 		configFilePath = path.Join(serverRootPath, "nginx.conf")
 	}
@@ -431,7 +693,7 @@ func GetConfig(serverRootPath, configFilePath string, quiteMode bool) (*Config,
 		configFilePath = filepath.Clean(filepath.Join(serverRootPath, configFilePath))
 	}
 
-	if !com.IsFile(configFilePath) {
+	if stat, err := fileSystem.Stat(configFilePath); err != nil || !stat.Mode().IsRegular() {
 		return nil, fmt.Errorf("could not find '%s' config file", configFilePath)
 	}
 
@@ -443,7 +705,7 @@ func GetConfig(serverRootPath, configFilePath string, quiteMode bool) (*Config,
 
 	parser := Config{
 		rawParser:  rawParser,
-		rawDumper:  &rawdumper.RawDumper{},
+		fileSystem: fileSystem,
 		serverRoot: serverRootPath,
 		configRoot: configFilePath,
 		quiteMode:  quiteMode,
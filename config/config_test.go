@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -61,7 +62,9 @@ func TestFindDirectives(t *testing.T) {
 
 	directives := config.FindDirectives("server_name")
 	assert.Len(t, directives, 9)
-	assert.Equal(t, directives[0].GetValues(), []string{"example.com"})
+	// sites-enabled are included before server blocks of nginx.conf
+	assert.Equal(t, directives[0].GetValues(), []string{"_"})
+	assert.Equal(t, directives[6].GetValues(), []string{"example.com"})
 }
 
 func TestDump(t *testing.T) {
@@ -77,6 +80,8 @@ func TestAddConfigFile(t *testing.T) {
 	config := parseConfig(t)
 	configFile, err := config.AddConfigFile(configFilePath)
 	assert.Nil(t, err)
+	// the file is looked up relative to the server root
+	assert.Equal(t, configFile.FilePath, config.GetConfigFile("sites-enabled/example3.com.conf").FilePath)
 
 	directive := NewDirective("directive", []string{"test"})
 	configFile.AddDirective(directive, true, true)
@@ -126,6 +131,44 @@ func TestParseFile(t *testing.T) {
 	assert.Len(t, serverBlocks, 1)
 }
 
+func TestIncludesInAnyContext(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf": `events {
+    include events.conf;
+}
+stream {
+    include stream/*.conf;
+}
+http {
+    upstream backend {
+        include upstream.conf;
+    }
+    include sites-enabled/*;
+}
+`,
+		"events.conf":                "worker_connections 1024;\n",
+		"stream/dns.conf":            "server {\n    listen 53 udp;\n    include snippets/stream-proxy.conf;\n}\n",
+		"upstream.conf":              "server 127.0.0.1:8080;\n",
+		"sites-enabled/a.com":        "server {\n    server_name a.com;\n    location ~ \\.php$ {\n        include snippets/fastcgi-php.conf;\n    }\n    if ($host = a.com) {\n        include snippets/redirect.conf;\n    }\n}\n",
+		"snippets/fastcgi-php.conf":  "include fastcgi.conf;\nfastcgi_index index.php;\n",
+		"snippets/redirect.conf":     "return 301 https://$host$request_uri;\n",
+		"snippets/stream-proxy.conf": "proxy_pass dns_backend;\n",
+		"fastcgi.conf":               "fastcgi_param SCRIPT_FILENAME $document_root$fastcgi_script_name;\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	for _, directiveName := range []string{"worker_connections", "proxy_pass", "server", "fastcgi_index", "fastcgi_param", "return"} {
+		assert.Lenf(t, config.FindDirectives(directiveName), 1, "directive %s not found", directiveName)
+	}
+
+	serverBlocks := config.FindServerBlocksByServerName("a.com")
+	assert.Len(t, serverBlocks, 1)
+	assert.Len(t, serverBlocks[0].FindDirectives("fastcgi_param"), 1)
+	assert.Len(t, serverBlocks[0].FindDirectives("return"), 1)
+}
+
 func parseConfig(t *testing.T) *Config {
 	config, err := GetConfig("../test/nginx", "", false)
 	assert.Nilf(t, err, "could not create config: %v", err)
@@ -142,3 +185,74 @@ func testWithConfigFileRollback(t *testing.T, configFilePath string, testFunc fu
 	err = os.WriteFile(configFilePath, configFileContent, 0666)
 	assert.Nil(t, err)
 }
+
+func TestAddIncludedConfigFile(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	httpBlocks := config.FindHttpBlocks()
+	assert.Len(t, httpBlocks, 1)
+
+	configFile, err := config.AddIncludedConfigFile("/etc/nginx/conf.d/new.conf", &httpBlocks[0].Block, false)
+	assert.Nil(t, err)
+
+	fragment, err := ParseString("server {\n    server_name new.example.com;\n}\n")
+	assert.Nil(t, err)
+	configFile.AddFragment(fragment, false)
+
+	assert.NotNil(t, config.GetConfigFile("new.conf"))
+	assert.Len(t, config.FindServerBlocksByServerName("new.example.com"), 1)
+	assert.Len(t, httpBlocks[0].FindServerBlocksByServerName("new.example.com"), 1)
+	assert.Len(t, configFile.IncludedBy(), 1)
+	assert.Equal(t, "/etc/nginx/nginx.conf", configFile.IncludedBy()[0].From)
+	assert.Equal(t, []string{"/etc/nginx/conf.d/new.conf", "/etc/nginx/nginx.conf"}, config.ModifiedFiles())
+
+	_, err = config.AddConfigFile("/etc/nginx/conf.d/new.conf")
+	assert.NotNil(t, err)
+
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/nginx.conf"].Data), "include conf.d/new.conf;")
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/conf.d/new.conf"].Data), "server_name new.example.com;")
+
+	config, err = GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	assert.Len(t, config.FindServerBlocksByServerName("new.example.com"), 1)
+}
+
+func TestFindConfigFile(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":            "http {\n    include conf.d/*;\n    include sites-enabled/*;\n}\n",
+		"conf.d/default":        "gzip on;\n",
+		"sites-enabled/default": "server {\n    listen 80 default_server;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	configFiles := config.ConfigFiles()
+	assert.Len(t, configFiles, 3)
+	assert.Equal(t, filepath.Join(serverRoot, "conf.d/default"), configFiles[0].FilePath)
+	assert.Equal(t, filepath.Join(serverRoot, "nginx.conf"), configFiles[1].FilePath)
+	assert.Equal(t, filepath.Join(serverRoot, "sites-enabled/default"), configFiles[2].FilePath)
+
+	configFile, err := config.FindConfigFile("sites-enabled/default")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(serverRoot, "sites-enabled/default"), configFile.FilePath)
+
+	configFile, err = config.FindConfigFile(filepath.Join(serverRoot, "conf.d/default"))
+	assert.Nil(t, err)
+	assert.Len(t, configFile.FindDirectives("gzip"), 1)
+
+	_, err = config.FindConfigFile("default")
+	var ambiguousErr *AmbiguousConfigFileError
+	assert.True(t, errors.As(err, &ambiguousErr))
+	assert.Len(t, ambiguousErr.FilePaths, 2)
+	assert.Nil(t, config.GetConfigFile("default"))
+
+	_, err = config.FindConfigFile("missing.conf")
+	assert.True(t, errors.Is(err, ErrConfigFileNotFound))
+	assert.NotNil(t, config.GetConfigFile("nginx.conf"))
+}
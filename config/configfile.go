@@ -1,7 +1,7 @@
 package config
 
 import (
-	"os"
+	"io"
 
 	"github.com/r2dtools/gonginxconf/internal/rawparser"
 )
@@ -16,7 +16,7 @@ func (c *ConfigFile) FindDirectives(directiveName string) []Directive {
 	var directives []Directive
 
 	for _, entry := range c.configFile.GetEntries() {
-		directives = append(directives, c.config.findDirectivesRecursively(directiveName, c.configFile, entry, true)...)
+		directives = append(directives, c.config.findDirectivesRecursively(directiveName, c.FilePath, c.configFile, entry, true, includeGuard{}, nil)...)
 	}
 
 	return directives
@@ -26,7 +26,7 @@ func (c *ConfigFile) FindBlocks(blockName string) []Block {
 	var blocks []Block
 
 	for _, entry := range c.configFile.GetEntries() {
-		blocks = append(blocks, c.config.findBlocksRecursively(blockName, c.FilePath, c.configFile, entry, true)...)
+		blocks = append(blocks, c.config.findBlocksRecursively(blockName, c.FilePath, c.configFile, entry, true, includeGuard{}, nil)...)
 	}
 
 	return blocks
@@ -57,15 +57,40 @@ func (c *ConfigFile) FindLocationBlocks() []LocationBlock {
 }
 
 func (c *ConfigFile) DeleteDirective(directive Directive) {
-	deleteDirective(c.configFile, directive)
+	c.config.edit(EditDeleteDirective, c.FilePath, c.configFile, directive.GetName(), func() {
+		deleteDirective(c.configFile, directive)
+	})
 }
 
 func (c *ConfigFile) DeleteDirectiveByName(directiveName string) {
-	deleteDirectiveByName(c.configFile, directiveName)
+	c.config.edit(EditDeleteDirective, c.FilePath, c.configFile, directiveName, func() {
+		deleteDirectiveByName(c.configFile, directiveName)
+	})
+}
+
+// AddDirective adds the directive to the top level of the file and returns it attached to the file, see Block.AddDirective
+func (c *ConfigFile) AddDirective(directive Directive, begining bool, endWithNewLine bool) Directive {
+	c.config.edit(EditAddDirective, c.FilePath, c.configFile, directive.GetName(), func() {
+		addDirective(c.configFile, directive, begining, endWithNewLine)
+	})
+
+	return Directive{
+		rawDirective: directive.rawDirective,
+		container:    c.configFile,
+		config:       c.config,
+		filePath:     c.FilePath,
+	}
+}
+
+func (c *ConfigFile) IsModified() bool {
+	return c.config.modifiedFiles[c.FilePath]
 }
 
-func (c *ConfigFile) AddDirective(directive Directive, begining bool, endWithNewLine bool) {
-	addDirective(c.configFile, directive, begining, endWithNewLine)
+// AddFragment copies blocks, directives and comments of a fragment created with ParseString into the file
+func (c *ConfigFile) AddFragment(fragment *ConfigFile, begining bool) {
+	c.config.edit(EditAddFragment, c.FilePath, c.configFile, "", func() {
+		addEntries(c.configFile, fragment.configFile.GetEntries(), begining)
+	})
 }
 
 func (c *ConfigFile) AddHttpBlock() HttpBlock {
@@ -77,41 +102,66 @@ func (c *ConfigFile) AddHttpBlock() HttpBlock {
 }
 
 func (c *ConfigFile) DeleteHttpBlock(httpBlock HttpBlock) {
-	deleteBlock(c.configFile, httpBlock.Block)
+	c.deleteBlock(httpBlock.Block)
 }
 
 func (c *ConfigFile) DeleteServerBlock(serverBlock ServerBlock) {
-	deleteBlock(c.configFile, serverBlock.Block)
+	c.deleteBlock(serverBlock.Block)
 }
 
 func (c *ConfigFile) DeleteUpstreamBlock(upstreamBlock ServerBlock) {
-	deleteBlock(c.configFile, upstreamBlock.Block)
+	c.deleteBlock(upstreamBlock.Block)
 }
 
 func (c *ConfigFile) addBlock(name string, parameters []string) Block {
-	return newBlock(c.configFile, c.config, name, parameters, false)
+	return newBlock(c.configFile, c.config, c.FilePath, name, parameters, false)
+}
+
+func (c *ConfigFile) deleteBlock(block Block) {
+	c.config.edit(EditDeleteBlock, c.FilePath, c.configFile, getBlockContextName(block.rawBlock), func() {
+		deleteBlock(c.configFile, block)
+	})
 }
 
 func (c *ConfigFile) Dump() error {
-	content, err := c.config.rawDumper.Dump(c.configFile)
+	return c.DumpWithOptions(DumpOptions{})
+}
+
+// ParseString parses a configuration snippet, e.g. "location /api { proxy_pass http://backend; }".
+// The returned ConfigFile is not attached to any file, its content can be copied
+// to an existing configuration with AddFragment.
+func ParseString(content string) (*ConfigFile, error) {
+	rawParser, err := rawparser.GetRawParser()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	file, err := os.OpenFile(c.FilePath, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0666)
+	tree, err := rawParser.Parse("", content)
 
 	if err != nil {
-		return err
+		return nil, newParseError("", content, err)
 	}
 
-	defer file.Close()
+	config := &Config{
+		rawParser:   rawParser,
+		fileSystem:  osFileSystem{},
+		parsedFiles: make(map[string]*rawparser.Config),
+	}
+
+	return &ConfigFile{
+		configFile: tree,
+		config:     config,
+	}, nil
+}
 
-	_, err = file.WriteString(content)
+// Parse reads a configuration snippet from r, see ParseString
+func Parse(r io.Reader) (*ConfigFile, error) {
+	content, err := io.ReadAll(r)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return ParseString(string(content))
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -57,6 +59,52 @@ func TestDeleteBlock(t *testing.T) {
 	})
 }
 
+func TestParseString(t *testing.T) {
+	fragment, err := ParseString("location /api {\n    proxy_pass http://backend;\n}\n")
+	assert.Nil(t, err)
+	assert.Empty(t, fragment.FilePath)
+
+	locationBlocks := fragment.FindLocationBlocks()
+	assert.Len(t, locationBlocks, 1)
+	assert.Equal(t, "/api", locationBlocks[0].GetLocationMatch())
+
+	directives := fragment.FindDirectives("proxy_pass")
+	assert.Len(t, directives, 1)
+	assert.Equal(t, 2, directives[0].GetPosition().Line)
+
+	fragment, err = Parse(strings.NewReader("server_tokens off"))
+	assert.Nil(t, fragment)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 1, parseErr.Line)
+}
+
+func TestAddFragment(t *testing.T) {
+	testWithConfigFileRollback(t, example2ConfigFilePath, func(t *testing.T) {
+		fragment, err := ParseString("# api\nlocation /api {\n    proxy_pass http://backend;\n}\nclient_max_body_size 16M;\n")
+		assert.Nil(t, err)
+
+		config, serverBlock := getServerBlock(t, "example2.com")
+		serverBlock.AddFragment(fragment, false)
+		serverBlock.AddFragment(fragment, true)
+		err = config.Dump()
+		assert.Nil(t, err)
+
+		_, serverBlock = getServerBlock(t, "example2.com")
+		locationBlocks := serverBlock.FindLocationBlocks()
+		assert.Len(t, locationBlocks, 3)
+		assert.Equal(t, "/api", locationBlocks[0].GetLocationMatch())
+		assert.Equal(t, "/api", locationBlocks[2].GetLocationMatch())
+
+		directives := serverBlock.FindDirectives("proxy_pass")
+		assert.Len(t, directives, 2)
+
+		directives[0].SetValue("http://other")
+		assert.Equal(t, "http://backend", fragment.FindDirectives("proxy_pass")[0].GetFirstValue())
+	})
+}
+
 func getConfigFile(t *testing.T, name string) *ConfigFile {
 	config := parseConfig(t)
 
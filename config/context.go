@@ -0,0 +1,103 @@
+package config
+
+import "strings"
+
+const contextSeparator = " > "
+
+// GetContext returns names of enclosing blocks starting from the outermost one, e.g.
+// ["http", "server[example.com]", "location[/api]"]. Includes are followed like in Parent.
+func (d *Directive) GetContext() []string {
+	return getContext(d.Ancestors())
+}
+
+// GetContextPath returns the context joined with " > ", e.g. "http > server[example.com] > location[/api]"
+func (d *Directive) GetContextPath() string {
+	return strings.Join(d.GetContext(), contextSeparator)
+}
+
+// GetContext returns names of blocks enclosing the block, see Directive.GetContext
+func (b *Block) GetContext() []string {
+	return getContext(b.Ancestors())
+}
+
+// GetContextPath returns the context of the block joined with " > "
+func (b *Block) GetContextPath() string {
+	return strings.Join(b.GetContext(), contextSeparator)
+}
+
+// FindDirectivesInContext returns directives which context starts with the given one. Elements of the context
+// are separated by ">" and match either a block name or its full context name, e.g. "stream",
+// "http > server" or "http > server[example.com] > location[/api]". An empty context matches everything.
+// A directive of a file included several times is returned for every place matching the context.
+func (c *Config) FindDirectivesInContext(context, directiveName string) []Directive {
+	var directives []Directive
+
+	for _, directive := range c.findDirectivesAtEveryInclude(directiveName) {
+		if matchContext(directive.GetContext(), context) {
+			directives = append(directives, directive)
+		}
+	}
+
+	return directives
+}
+
+// FindBlocksInContext returns blocks which context starts with the given one, see FindDirectivesInContext
+func (c *Config) FindBlocksInContext(context, blockName string) []Block {
+	var blocks []Block
+
+	for _, block := range c.findBlocksAtEveryInclude(blockName) {
+		if matchContext(block.GetContext(), context) {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+// FindServerBlocksInContext allows to tell server blocks of http and stream apart, e.g. FindServerBlocksInContext("http")
+func (c *Config) FindServerBlocksInContext(context string) []ServerBlock {
+	var serverBlocks []ServerBlock
+
+	for _, block := range c.FindBlocksInContext(context, serverBlockName) {
+		serverBlocks = append(serverBlocks, ServerBlock{
+			Block: block,
+		})
+	}
+
+	return serverBlocks
+}
+
+func getContext(ancestors []Container) []string {
+	var context []string
+
+	for index := len(ancestors) - 1; index >= 0; index-- {
+		if block, ok := getContainerBlock(ancestors[index]); ok {
+			context = append(context, getBlockContextName(block.rawBlock))
+		}
+	}
+
+	return context
+}
+
+func matchContext(context []string, pattern string) bool {
+	if strings.TrimSpace(pattern) == "" {
+		return true
+	}
+
+	parts := strings.Split(pattern, ">")
+
+	if len(parts) > len(context) {
+		return false
+	}
+
+	for index, part := range parts {
+		part = strings.TrimSpace(part)
+		identifier, _, _ := strings.Cut(context[index], "[")
+
+		if part != context[index] && part != identifier {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextPath(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":          "stream {\n    server {\n        listen 53 udp;\n    }\n}\nhttp {\n    include sites-enabled/*;\n}\n",
+		"sites-enabled/a.com": "server {\n    server_name a.com;\n    listen 80;\n    location /api {\n        proxy_pass http://backend;\n    }\n}\n",
+		"sites-enabled/b.com": "server {\n    server_name b.com www.b.com;\n    listen 8080;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	directives := config.FindDirectives("listen")
+	assert.Len(t, directives, 3)
+
+	var contextPaths []string
+
+	for _, directive := range directives {
+		contextPaths = append(contextPaths, directive.GetContextPath())
+	}
+
+	assert.ElementsMatch(t, []string{"stream > server", "http > server[a.com]", "http > server[b.com www.b.com]"}, contextPaths)
+
+	directives = config.FindDirectives("proxy_pass")
+	assert.Len(t, directives, 1)
+	assert.Equal(t, []string{"http", "server[a.com]", "location[/api]"}, directives[0].GetContext())
+	assert.Equal(t, "http > server[a.com] > location[/api]", directives[0].GetContextPath())
+
+	directives = config.FindDirectivesInContext("stream", "listen")
+	assert.Len(t, directives, 1)
+	assert.Equal(t, []string{"53", "udp"}, directives[0].GetValues())
+
+	assert.Len(t, config.FindDirectivesInContext("http > server", "listen"), 2)
+	assert.Len(t, config.FindDirectivesInContext("http > server[b.com www.b.com]", "listen"), 1)
+	assert.Len(t, config.FindDirectivesInContext("", "listen"), 3)
+	assert.Empty(t, config.FindDirectivesInContext("http > server > location", "listen"))
+
+	blocks := config.FindBlocksInContext("http > server[a.com]", "location")
+	assert.Len(t, blocks, 1)
+	assert.Equal(t, "http > server[a.com]", blocks[0].GetContextPath())
+
+	assert.Len(t, config.FindServerBlocks(), 3)
+	assert.Len(t, config.FindServerBlocksInContext("http"), 2)
+	assert.Len(t, config.FindServerBlocksInContext("stream"), 1)
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"github.com/r2dtools/gonginxconf/internal/unifieddiff"
+)
+
+const devNull = "/dev/null"
+
+type FileDiff struct {
+	FilePath string
+	// IsNew is set for files that do not exist yet, e.g. created with AddConfigFile
+	IsNew bool
+	// IsEmptied is set when the file would have no content after Dump
+	IsEmptied bool
+	// IsRemoved is set for files removed with RemoveConfigFile
+	IsRemoved bool
+	// RenamedFrom is the path of the file on disk if it was renamed with RenameConfigFile
+	RenamedFrom string
+	// Diff is a unified diff between the file content on disk and the content Dump would write
+	Diff string
+}
+
+// Diff returns changes Dump would make without writing anything. Modified files including files
+// created with AddConfigFile are compared, files which content would not change are skipped.
+// Removed and renamed files are reported too.
+func (c *Config) Diff() ([]FileDiff, error) {
+	trees := make(map[string]*rawparser.Config)
+
+	for filePath := range c.modifiedFiles {
+		if tree, ok := c.parsedFiles[filePath]; ok {
+			trees[filePath] = tree
+		}
+	}
+
+	for filePath := range c.renamedFiles {
+		if tree, ok := c.parsedFiles[filePath]; ok {
+			trees[filePath] = tree
+		}
+	}
+
+	for filePath := range c.removedFiles {
+		trees[filePath] = nil
+	}
+
+	filePaths := make([]string, 0, len(trees))
+
+	for filePath := range trees {
+		filePaths = append(filePaths, filePath)
+	}
+
+	sort.Strings(filePaths)
+
+	var diffs []FileDiff
+
+	for _, filePath := range filePaths {
+		diff, err := c.diffFile(filePath, trees[filePath])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if diff.Diff != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return diffs, nil
+}
+
+// Diff compares the file with its content on disk, Diff field of the result is empty if there are no changes
+func (c *ConfigFile) Diff() (FileDiff, error) {
+	return c.config.diffFile(c.FilePath, c.configFile)
+}
+
+func (c *Config) diffFile(filePath string, tree *rawparser.Config) (FileDiff, error) {
+	diff := FileDiff{
+		FilePath: filePath,
+	}
+
+	var content string
+	originalPath := filePath
+	newName := "b/" + strings.TrimPrefix(filePath, "/")
+
+	if tree == nil {
+		diff.IsRemoved = true
+		newName = devNull
+	} else {
+		var err error
+
+		if content, err = dumpTree(tree); err != nil {
+			return diff, err
+		}
+	}
+
+	if renamedFrom, ok := c.renamedFiles[filePath]; ok {
+		diff.RenamedFrom = renamedFrom
+		originalPath = renamedFrom
+	}
+
+	oldName := "a/" + strings.TrimPrefix(originalPath, "/")
+	original, err := c.fileSystem.ReadFile(originalPath)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		// a removed file can already be missing on disk, it is not new then
+		diff.IsNew = tree != nil
+		oldName = devNull
+	} else if err != nil {
+		return diff, err
+	}
+
+	diff.IsEmptied = tree != nil && content == "" && len(original) != 0
+	diff.Diff = unifieddiff.Diff(oldName, newName, string(original), content, unifieddiff.DefaultContextLines)
+
+	// new and renamed files have to be reported even if their content is the same
+	if (diff.IsNew || diff.RenamedFrom != "") && diff.Diff == "" {
+		diff.Diff = "--- " + oldName + "\n+++ " + newName + "\n"
+	}
+
+	return diff, nil
+}
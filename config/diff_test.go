@@ -0,0 +1,93 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigDiff(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+	originalContent := string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data)
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	diffs, err := config.Diff()
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+
+	serverBlocks := config.FindServerBlocksByServerName("example.com")
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].AddDirective(NewDirective("listen", []string{"80"}), true, false)
+
+	configFile := config.GetConfigFile("fastcgi.conf")
+	assert.NotNil(t, configFile)
+	configFile.DeleteDirectiveByName("fastcgi_param")
+
+	newConfigFile, err := config.AddConfigFile("/etc/nginx/conf.d/new.conf")
+	assert.Nil(t, err)
+	newConfigFile.AddDirective(NewDirective("gzip", []string{"on"}), false, true)
+
+	diffs, err = config.Diff()
+	assert.Nil(t, err)
+	assert.Len(t, diffs, 3)
+
+	assert.Equal(t, "/etc/nginx/conf.d/new.conf", diffs[0].FilePath)
+	assert.True(t, diffs[0].IsNew)
+	assert.Equal(t, "--- /dev/null\n+++ b/etc/nginx/conf.d/new.conf\n@@ -0,0 +1,2 @@\n+\n+gzip on;\n", diffs[0].Diff)
+
+	assert.Equal(t, "/etc/nginx/sites-enabled/example.com.conf", diffs[1].FilePath)
+	assert.False(t, diffs[1].IsNew)
+	assert.False(t, diffs[1].IsEmptied)
+	assert.Equal(t, `--- a/etc/nginx/sites-enabled/example.com.conf
++++ b/etc/nginx/sites-enabled/example.com.conf
+@@ -1,4 +1,5 @@
+ server {
++    listen 80;
+     server_name example.com;
+     location ~ \.php$ {
+         include snippets/fastcgi.conf;
+`, diffs[1].Diff)
+
+	assert.Equal(t, "/etc/nginx/snippets/fastcgi.conf", diffs[2].FilePath)
+	assert.True(t, diffs[2].IsEmptied)
+
+	// nothing is written
+	assert.Equal(t, originalContent, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data))
+	assert.NotContains(t, fsys.MapFS, "etc/nginx/conf.d/new.conf")
+
+	diff, err := newConfigFile.Diff()
+	assert.Nil(t, err)
+	assert.Equal(t, diffs[0], diff)
+}
+
+func TestRemovedFileDiff(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	err = config.RemoveConfigFile("snippets/fastcgi.conf")
+	assert.Nil(t, err)
+
+	diffs, err := config.Diff()
+	assert.Nil(t, err)
+	assert.Len(t, diffs, 2)
+	assert.True(t, diffs[1].IsRemoved)
+	assert.False(t, diffs[1].IsEmptied)
+	assert.False(t, diffs[1].IsNew)
+
+	// the file was already deleted on disk, there is nothing to remove
+	delete(fsys.MapFS, "etc/nginx/snippets/fastcgi.conf")
+
+	diff, err := config.diffFile("/etc/nginx/snippets/fastcgi.conf", nil)
+	assert.Nil(t, err)
+	assert.True(t, diff.IsRemoved)
+	assert.False(t, diff.IsNew)
+	assert.Empty(t, diff.Diff)
+
+	diffs, err = config.Diff()
+	assert.Nil(t, err)
+	assert.Len(t, diffs, 1)
+}
@@ -10,6 +10,10 @@ import (
 type Directive struct {
 	rawDirective *rawparser.Directive
 	container    entryContainer
+	config       *Config
+	filePath     string
+	// includedBy is the include directive the directive was found through
+	includedBy *Directive
 }
 
 func (d *Directive) GetName() string {
@@ -20,6 +24,10 @@ func (d *Directive) GetValues() []string {
 	return d.rawDirective.GetExpressions()
 }
 
+func (d *Directive) GetPosition() Position {
+	return newPosition(d.rawDirective.Pos)
+}
+
 func (d *Directive) GetFirstValue() string {
 	values := d.GetValues()
 
@@ -34,11 +42,13 @@ func (d *Directive) AddValue(expression string) {
 	expressions := d.rawDirective.GetExpressions()
 	expressions = append(expressions, expression)
 
-	d.rawDirective.SetValues(expressions)
+	d.SetValues(expressions)
 }
 
 func (d *Directive) SetValues(expressions []string) {
-	d.rawDirective.SetValues(expressions)
+	d.config.edit(EditSetValues, d.filePath, d.container, d.GetName(), func() {
+		d.rawDirective.SetValues(expressions)
+	})
 }
 
 func (d *Directive) SetValue(expression string) {
@@ -138,13 +148,14 @@ func (d *Directive) SetComments(comments []string) {
 		nIndex = rIndex
 	}
 
-	if nIndex != index {
-		entries = slices.Delete(entries, nIndex, index)
-	}
-
-	entries = slices.Insert(entries, nIndex, pEntries...)
+	d.config.edit(EditSetComments, d.filePath, d.container, d.GetName(), func() {
+		if nIndex != index {
+			entries = slices.Delete(entries, nIndex, index)
+		}
 
-	setEntries(d.container, entries)
+		entries = slices.Insert(entries, nIndex, pEntries...)
+		setEntries(d.container, entries)
+	})
 }
 
 func (d *Directive) findInlineComment(entry, nextEntry *rawparser.Entry) *Comment {
@@ -169,10 +180,6 @@ func (d *Directive) createComment(rawComment *rawparser.Comment, position Commen
 	}
 }
 
-func (d *Directive) setContainer(container entryContainer) {
-	d.container = container
-}
-
 func NewDirective(name string, values []string) Directive {
 	directiveValues := []*rawparser.Value{}
 
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +13,7 @@ func TestDirective(t *testing.T) {
 	directives := config.FindDirectives("ssl_certificate")
 	assert.Len(t, directives, 5)
 
-	directive := directives[3]
+	directive := directives[0]
 	assert.Equal(t, "ssl_certificate", directive.GetName())
 	assert.Equal(t, "/opt/webmng/test/certificate/example.com.crt", directive.GetFirstValue())
 }
@@ -66,3 +67,34 @@ func TestSetDirectiveComments(t *testing.T) {
 		assert.Equal(t, "test comment1", comments[0].Content)
 	})
 }
+
+func TestDirectivePosition(t *testing.T) {
+	_, directive := getServerBlockDirective(t, "example2.com", "ssl_certificate_key")
+	position := directive.GetPosition()
+
+	absPath, err := filepath.Abs(example2ConfigFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, absPath, position.FilePath)
+	assert.Equal(t, 77, position.Line)
+	assert.Equal(t, 5, position.Column)
+
+	directive = NewDirective("test", []string{"test_value"})
+	assert.False(t, directive.GetPosition().IsValid())
+}
+
+func TestIncludedDirectivePosition(t *testing.T) {
+	config := parseConfig(t)
+	serverBlocks := config.FindServerBlocksByServerName("example.com")
+	assert.Len(t, serverBlocks, 2)
+
+	directives := serverBlocks[0].FindDirectives("gzip_comp_level")
+	assert.Len(t, directives, 1)
+
+	absPath, err := filepath.Abs("../test/nginx/nginxconfig.io/general.conf")
+	assert.Nil(t, err)
+
+	position := directives[0].GetPosition()
+	assert.Equal(t, absPath, position.FilePath)
+	assert.Equal(t, 30, position.Line)
+	assert.Equal(t, 1, position.Column)
+}
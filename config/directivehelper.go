@@ -44,7 +44,6 @@ func deleteDirectiveInEntityContainer(c entryContainer, callback func(directive
 
 func addDirective(c entryContainer, directive Directive, toBegining bool, endWithNewLine bool) {
 	entries := c.GetEntries()
-	directive.setContainer(c)
 	entry := &rawparser.Entry{
 		Directive: directive.rawDirective,
 	}
@@ -0,0 +1,485 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"github.com/r2dtools/gonginxconf/internal/rawdumper"
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	backupFileExtension = ".bak"
+	newFileMode         = 0644
+)
+
+type DumpOptions struct {
+	// Backup keeps the previous content of every rewritten file next to it with the .bak extension
+	Backup bool
+	// Force overwrites files that were changed on disk after they had been parsed
+	Force bool
+}
+
+type fileWrite struct {
+	filePath string
+	content  []byte
+	original []byte
+	exists   bool
+	mode     fs.FileMode
+	// backup is the write of the .bak file made before the file itself was written
+	backup *fileWrite
+}
+
+// fileChanges are applied by Dump as a single unit
+type fileChanges struct {
+	trees map[string]*rawparser.Config
+	// renames maps new paths of files to their paths on disk
+	renames  map[string]string
+	removals []string
+	// symlinks maps paths of symbolic links to create to their targets
+	symlinks map[string]string
+	unlinks  []string
+}
+
+type fileRename struct {
+	*fileWrite
+	newFilePath string
+}
+
+type fileLink struct {
+	filePath string
+	target   string
+}
+
+// preparedFileChanges are applied in the order of the fields and reverted in the reverse order
+type preparedFileChanges struct {
+	renames  []fileRename
+	removals []*fileWrite
+	unlinks  []fileLink
+	symlinks []fileLink
+	writes   []*fileWrite
+}
+
+// DumpWithOptions writes modified files and applies removals, renames and symbolic links of files
+// as a single unit: if any change can not be applied, changes that were already made are reverted.
+// ConflictError is returned without changing anything if some of the files were changed on disk
+// after they had been parsed, unless the Force option is set.
+func (c *Config) DumpWithOptions(options DumpOptions) error {
+	changes := fileChanges{
+		trees:    make(map[string]*rawparser.Config),
+		renames:  c.renamedFiles,
+		removals: maps.Keys(c.removedFiles),
+		symlinks: c.newSymlinks,
+		unlinks:  maps.Keys(c.removedSymlinks),
+	}
+
+	for filePath := range c.modifiedFiles {
+		if tree, ok := c.parsedFiles[filePath]; ok {
+			changes.trees[filePath] = tree
+		}
+	}
+
+	if err := c.applyFileChanges(changes, options); err != nil {
+		return err
+	}
+
+	c.modifiedFiles = make(map[string]bool)
+	c.newFiles = make(map[string]bool)
+	c.removedFiles = make(map[string]bool)
+	c.renamedFiles = make(map[string]string)
+	c.newSymlinks = make(map[string]string)
+	c.removedSymlinks = make(map[string]bool)
+
+	return nil
+}
+
+// DumpWithOptions writes the file even if it was not modified, a pending rename of the file is applied too
+func (c *ConfigFile) DumpWithOptions(options DumpOptions) error {
+	changes := fileChanges{
+		trees: map[string]*rawparser.Config{c.FilePath: c.configFile},
+	}
+
+	if originalPath, ok := c.config.renamedFiles[c.FilePath]; ok {
+		changes.renames = map[string]string{c.FilePath: originalPath}
+	}
+
+	if target, ok := c.config.newSymlinks[c.FilePath]; ok {
+		changes.symlinks = map[string]string{c.FilePath: target}
+	}
+
+	if err := c.config.applyFileChanges(changes, options); err != nil {
+		return err
+	}
+
+	delete(c.config.modifiedFiles, c.FilePath)
+	delete(c.config.newFiles, c.FilePath)
+	delete(c.config.renamedFiles, c.FilePath)
+	delete(c.config.newSymlinks, c.FilePath)
+
+	return nil
+}
+
+func (c *Config) applyFileChanges(changes fileChanges, options DumpOptions) error {
+	prepared, err := c.prepareFileChanges(changes, options)
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.applyPreparedFileChanges(prepared, options); err != nil {
+		return err
+	}
+
+	var dumpedFiles []string
+
+	for _, rename := range prepared.renames {
+		delete(c.fileStates, rename.filePath)
+		c.recordFileState(rename.newFilePath, rename.original)
+		dumpedFiles = append(dumpedFiles, rename.filePath, rename.newFilePath)
+	}
+
+	for _, removal := range prepared.removals {
+		delete(c.fileStates, removal.filePath)
+		dumpedFiles = append(dumpedFiles, removal.filePath)
+	}
+
+	for _, unlink := range prepared.unlinks {
+		delete(c.fileStates, unlink.filePath)
+		dumpedFiles = append(dumpedFiles, unlink.filePath)
+	}
+
+	for _, write := range prepared.writes {
+		c.recordFileState(write.filePath, write.content)
+		dumpedFiles = append(dumpedFiles, write.filePath)
+	}
+
+	for _, transaction := range c.transactions {
+		for _, filePath := range dumpedFiles {
+			if state, ok := c.fileStates[filePath]; ok {
+				transaction.dumpedFiles[filePath] = &state
+			} else {
+				transaction.dumpedFiles[filePath] = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// prepareFileChanges reads and renders everything first, so that nothing is changed if any file is not valid
+func (c *Config) prepareFileChanges(changes fileChanges, options DumpOptions) (*preparedFileChanges, error) {
+	var conflicts []string
+
+	prepared := &preparedFileChanges{}
+	// files that are written under a new path after they are renamed or linked
+	originals := make(map[string]*fileWrite)
+
+	renamedPaths := maps.Keys(changes.renames)
+	sort.Strings(renamedPaths)
+
+	for _, newFilePath := range renamedPaths {
+		oldFilePath := changes.renames[newFilePath]
+		original, err := c.prepareFileWrite(oldFilePath, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !original.exists {
+			return nil, fmt.Errorf("could not rename %s: %w", oldFilePath, fs.ErrNotExist)
+		}
+
+		if !options.Force {
+			if c.fileExists(newFilePath) {
+				conflicts = append(conflicts, newFilePath)
+			} else if c.isChangedOnDisk(oldFilePath, true, original.original) {
+				conflicts = append(conflicts, oldFilePath)
+			}
+		}
+
+		prepared.renames = append(prepared.renames, fileRename{fileWrite: original, newFilePath: newFilePath})
+		originals[newFilePath] = original
+	}
+
+	removedPaths := slices.Clone(changes.removals)
+	sort.Strings(removedPaths)
+
+	for _, filePath := range removedPaths {
+		removal, err := c.prepareFileWrite(filePath, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !removal.exists {
+			continue
+		}
+
+		if !options.Force && c.isChangedOnDisk(filePath, true, removal.original) {
+			conflicts = append(conflicts, filePath)
+		}
+
+		prepared.removals = append(prepared.removals, removal)
+	}
+
+	unlinkedPaths := slices.Clone(changes.unlinks)
+	sort.Strings(unlinkedPaths)
+
+	for _, filePath := range unlinkedPaths {
+		target, err := c.fileSystem.ReadLink(filePath)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		prepared.unlinks = append(prepared.unlinks, fileLink{filePath: filePath, target: target})
+	}
+
+	linkedPaths := maps.Keys(changes.symlinks)
+	sort.Strings(linkedPaths)
+
+	for _, filePath := range linkedPaths {
+		target := changes.symlinks[filePath]
+		original, err := c.prepareFileWrite(getSymlinkTargetPath(filePath, target), nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !options.Force && (c.fileExists(filePath) || c.isChangedOnDisk(filePath, original.exists, original.original)) {
+			conflicts = append(conflicts, filePath)
+		}
+
+		prepared.symlinks = append(prepared.symlinks, fileLink{filePath: filePath, target: target})
+		originals[filePath] = original
+	}
+
+	filePaths := maps.Keys(changes.trees)
+	sort.Strings(filePaths)
+
+	for _, filePath := range filePaths {
+		content, err := dumpTree(changes.trees[filePath])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if original, ok := originals[filePath]; ok {
+			write := *original
+			write.filePath = filePath
+			write.content = []byte(content)
+			prepared.writes = append(prepared.writes, &write)
+
+			continue
+		}
+
+		write, err := c.prepareFileWrite(filePath, []byte(content))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !options.Force && c.isChangedOnDisk(filePath, write.exists, write.original) {
+			conflicts = append(conflicts, filePath)
+		}
+
+		prepared.writes = append(prepared.writes, write)
+	}
+
+	if len(conflicts) != 0 {
+		return nil, &ConflictError{FilePaths: conflicts}
+	}
+
+	return prepared, nil
+}
+
+// applyPreparedFileChanges reverts everything that was changed if any change fails
+func (c *Config) applyPreparedFileChanges(prepared *preparedFileChanges, options DumpOptions) error {
+	var renamed, removed, unlinked, linked, written int
+
+	err := func() error {
+		for ; renamed < len(prepared.renames); renamed++ {
+			rename := prepared.renames[renamed]
+
+			if err := c.fileSystem.Rename(rename.filePath, rename.newFilePath); err != nil {
+				return err
+			}
+		}
+
+		for ; removed < len(prepared.removals); removed++ {
+			if err := c.fileSystem.Remove(prepared.removals[removed].filePath); err != nil {
+				return err
+			}
+		}
+
+		for ; unlinked < len(prepared.unlinks); unlinked++ {
+			if err := c.fileSystem.Remove(prepared.unlinks[unlinked].filePath); err != nil {
+				return err
+			}
+		}
+
+		for ; linked < len(prepared.symlinks); linked++ {
+			symlink := prepared.symlinks[linked]
+
+			if err := c.fileSystem.Symlink(symlink.target, symlink.filePath); err != nil {
+				return err
+			}
+		}
+
+		for ; written < len(prepared.writes); written++ {
+			if err := c.writeFile(prepared.writes[written], options); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	if err == nil {
+		return nil
+	}
+
+	var rollbackErrs []error
+
+	if rollbackErr := c.rollbackFileWrites(prepared.writes[:written]); rollbackErr != nil {
+		rollbackErrs = append(rollbackErrs, rollbackErr)
+	}
+
+	for index := linked - 1; index >= 0; index-- {
+		if rollbackErr := c.fileSystem.Remove(prepared.symlinks[index].filePath); rollbackErr != nil {
+			rollbackErrs = append(rollbackErrs, rollbackErr)
+		}
+	}
+
+	for index := unlinked - 1; index >= 0; index-- {
+		unlink := prepared.unlinks[index]
+
+		if rollbackErr := c.fileSystem.Symlink(unlink.target, unlink.filePath); rollbackErr != nil {
+			rollbackErrs = append(rollbackErrs, rollbackErr)
+		}
+	}
+
+	if rollbackErr := c.rollbackFileWrites(prepared.removals[:removed]); rollbackErr != nil {
+		rollbackErrs = append(rollbackErrs, rollbackErr)
+	}
+
+	for index := renamed - 1; index >= 0; index-- {
+		rename := prepared.renames[index]
+
+		if rollbackErr := c.fileSystem.Rename(rename.newFilePath, rename.filePath); rollbackErr != nil {
+			rollbackErrs = append(rollbackErrs, rollbackErr)
+		}
+	}
+
+	if len(rollbackErrs) != 0 {
+		return errors.Join(err, fmt.Errorf("rollback failed: %w", errors.Join(rollbackErrs...)))
+	}
+
+	return err
+}
+
+func (c *Config) fileExists(filePath string) bool {
+	_, err := c.fileSystem.Lstat(filePath)
+
+	return err == nil
+}
+
+func (c *Config) prepareFileWrite(filePath string, content []byte) (*fileWrite, error) {
+	write := &fileWrite{
+		filePath: filePath,
+		content:  content,
+		mode:     newFileMode,
+	}
+
+	original, err := c.fileSystem.ReadFile(filePath)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return write, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	write.original = original
+	write.exists = true
+
+	if stat, err := c.fileSystem.Stat(filePath); err == nil {
+		write.mode = stat.Mode().Perm()
+	}
+
+	return write, nil
+}
+
+// writeFile reverts the backup itself if the file can not be written
+func (c *Config) writeFile(write *fileWrite, options DumpOptions) error {
+	if options.Backup && write.exists {
+		backup, err := c.prepareFileWrite(write.filePath+backupFileExtension, write.original)
+
+		if err != nil {
+			return err
+		}
+
+		if err = c.fileSystem.WriteFile(backup.filePath, backup.content, write.mode); err != nil {
+			return err
+		}
+
+		write.backup = backup
+	}
+
+	err := c.fileSystem.WriteFile(write.filePath, write.content, write.mode)
+
+	if err == nil || write.backup == nil {
+		return err
+	}
+
+	if rollbackErr := c.rollbackFileWrites([]*fileWrite{write.backup}); rollbackErr != nil {
+		return errors.Join(err, fmt.Errorf("rollback failed: %w", rollbackErr))
+	}
+
+	return err
+}
+
+// rollbackFileWrites restores original files and their backups
+func (c *Config) rollbackFileWrites(writes []*fileWrite) error {
+	var errs []error
+
+	for _, write := range writes {
+		var err error
+
+		if write.backup != nil {
+			if backupErr := c.rollbackFileWrites([]*fileWrite{write.backup}); backupErr != nil {
+				errs = append(errs, backupErr)
+			}
+
+			write.backup = nil
+		}
+
+		if write.exists {
+			err = c.fileSystem.WriteFile(write.filePath, write.original, write.mode)
+		} else {
+			err = c.fileSystem.Remove(write.filePath)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// dumpTree creates a dumper per call because it keeps the nesting level while dumping
+func dumpTree(tree *rawparser.Config) (string, error) {
+	dumper := &rawdumper.RawDumper{}
+
+	return dumper.Dump(tree)
+}
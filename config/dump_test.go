@@ -0,0 +1,259 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestWriteFailed = errors.New("write failed")
+
+type failingMapFS struct {
+	writableMapFS
+	failingFileName string
+}
+
+func (m failingMapFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if name == m.failingFileName {
+		return errTestWriteFailed
+	}
+
+	return m.writableMapFS.WriteFile(name, data, perm)
+}
+
+func TestDumpWithBackup(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":                 "http {\n    include sites-enabled/*.conf;\n}\n",
+		"sites-enabled/example.conf": "server {\n    server_name example.com;\n}\n",
+	})
+	exampleConfigPath := filepath.Join(serverRoot, "sites-enabled/example.conf")
+	err := os.Chmod(exampleConfigPath, 0640)
+	assert.Nil(t, err)
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	serverBlocks := config.FindServerBlocksByServerName("example.com")
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].AddDirective(NewDirective("listen", []string{"80"}), false, true)
+
+	err = config.DumpWithOptions(DumpOptions{Backup: true})
+	assert.Nil(t, err)
+
+	content, err := os.ReadFile(exampleConfigPath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "listen 80;")
+
+	backupContent, err := os.ReadFile(exampleConfigPath + ".bak")
+	assert.Nil(t, err)
+	assert.Equal(t, "server {\n    server_name example.com;\n}\n", string(backupContent))
+
+	stat, err := os.Stat(exampleConfigPath)
+	assert.Nil(t, err)
+	assert.Equal(t, fs.FileMode(0640), stat.Mode().Perm())
+
+	// no temporary files are left
+	entries, err := os.ReadDir(filepath.Dir(exampleConfigPath))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestDumpKeepsSymlink(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":                   "http {\n    include sites-enabled/*;\n}\n",
+		"sites-available/example.conf": "server {\n    server_name example.com;\n}\n",
+	})
+	err := os.Mkdir(filepath.Join(serverRoot, "sites-enabled"), 0755)
+	assert.Nil(t, err)
+
+	linkPath := filepath.Join(serverRoot, "sites-enabled/example.conf")
+	err = os.Symlink("../sites-available/example.conf", linkPath)
+	assert.Nil(t, err)
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	serverBlocks := config.FindServerBlocksByServerName("example.com")
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].AddDirective(NewDirective("listen", []string{"80"}), false, true)
+
+	err = config.Dump()
+	assert.Nil(t, err)
+
+	stat, err := os.Lstat(linkPath)
+	assert.Nil(t, err)
+	assert.Equal(t, fs.ModeSymlink, stat.Mode().Type())
+
+	content, err := os.ReadFile(filepath.Join(serverRoot, "sites-available/example.conf"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "listen 80;")
+}
+
+func TestDumpRollback(t *testing.T) {
+	mapFS := getTestMapFS()
+	mapFS["etc/nginx/sites-enabled/zexample.com.conf"] = &fstest.MapFile{
+		Data: []byte("server {\n    server_name zexample.com;\n}\n"),
+	}
+	originalContent := string(mapFS["etc/nginx/sites-enabled/example.com.conf"].Data)
+	fsys := failingMapFS{
+		writableMapFS:   writableMapFS{MapFS: mapFS},
+		failingFileName: "etc/nginx/sites-enabled/zexample.com.conf",
+	}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	for _, serverBlock := range config.FindServerBlocks() {
+		serverBlock.AddDirective(NewDirective("listen", []string{"80"}), false, true)
+	}
+
+	assert.Len(t, config.ModifiedFiles(), 2)
+
+	err = config.Dump()
+	assert.True(t, errors.Is(err, errTestWriteFailed))
+	assert.Equal(t, originalContent, string(mapFS["etc/nginx/sites-enabled/example.com.conf"].Data))
+}
+
+func TestDumpRollbackRestoresBackups(t *testing.T) {
+	mapFS := getTestMapFS()
+	mapFS["etc/nginx/sites-enabled/zexample.com.conf"] = &fstest.MapFile{
+		Data: []byte("server {\n    server_name zexample.com;\n}\n"),
+	}
+	mapFS["etc/nginx/sites-enabled/example.com.conf.bak"] = &fstest.MapFile{Data: []byte("previous backup\n")}
+	fsys := failingMapFS{
+		writableMapFS:   writableMapFS{MapFS: mapFS},
+		failingFileName: "etc/nginx/sites-enabled/zexample.com.conf",
+	}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	for _, serverBlock := range config.FindServerBlocks() {
+		serverBlock.AddDirective(NewDirective("listen", []string{"80"}), false, true)
+	}
+
+	err = config.DumpWithOptions(DumpOptions{Backup: true})
+	assert.True(t, errors.Is(err, errTestWriteFailed))
+	assert.Equal(t, "previous backup\n", string(mapFS["etc/nginx/sites-enabled/example.com.conf.bak"].Data))
+	assert.NotContains(t, mapFS, "etc/nginx/sites-enabled/zexample.com.conf.bak")
+}
+
+func TestDumpModifiedFilesOnly(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+	fsys.MapFS["etc/nginx/mime.types"] = &fstest.MapFile{Data: []byte("types {\n  text/html html;\n}\n")}
+	fsys.MapFS["etc/nginx/nginx.conf"].Data = []byte("include mime.types;\nhttp {\n    include /etc/nginx/sites-enabled/*.conf;\n}\n")
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	assert.Empty(t, config.ModifiedFiles())
+
+	directives := config.FindDirectives("fastcgi_param")
+	assert.Len(t, directives, 1)
+	directives[0].SetValues([]string{"SCRIPT_FILENAME", "$realpath_root$fastcgi_script_name"})
+
+	configFile := config.GetConfigFile("example.com.conf")
+	assert.NotNil(t, configFile)
+	serverBlocks := configFile.FindServerBlocks()
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].FindLocationBlocks()[0].SetLocationMatch("\\.php7?$")
+	assert.True(t, configFile.IsModified())
+
+	assert.Equal(t, []string{"/etc/nginx/sites-enabled/example.com.conf", "/etc/nginx/snippets/fastcgi.conf"}, config.ModifiedFiles())
+
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.Empty(t, config.ModifiedFiles())
+	assert.False(t, configFile.IsModified())
+
+	// untouched files keep their formatting
+	assert.Equal(t, "types {\n  text/html html;\n}\n", string(fsys.MapFS["etc/nginx/mime.types"].Data))
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/snippets/fastcgi.conf"].Data), "$realpath_root")
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data), "location ~ \\.php7?$")
+}
+
+func TestDumpAddedDirectiveChanges(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	serverBlock := config.FindServerBlocksByServerName("example.com")[0]
+	directive := serverBlock.AddDirective(NewDirective("listen", []string{"80"}), false, true)
+	fileDirective := config.GetConfigFile("fastcgi.conf").AddDirective(NewDirective("fastcgi_index", []string{"index.php"}), false, true)
+
+	err = config.Dump()
+	assert.Nil(t, err)
+
+	// the returned directives are attached to the configuration
+	directive.SetValues([]string{"443", "ssl"})
+	fileDirective.SetValue("index.html")
+	assert.Equal(t, []string{"/etc/nginx/sites-enabled/example.com.conf", "/etc/nginx/snippets/fastcgi.conf"}, config.ModifiedFiles())
+	assert.Equal(t, "http > server[example.com]", directive.GetContextPath())
+
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data), "listen 443 ssl;")
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/snippets/fastcgi.conf"].Data), "fastcgi_index index.html;")
+}
+
+func TestDumpConflict(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+	exampleFileName := "etc/nginx/sites-enabled/example.com.conf"
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	for _, serverBlock := range config.FindServerBlocks() {
+		serverBlock.AddDirective(NewDirective("listen", []string{"80"}), false, true)
+	}
+
+	directives := config.FindDirectives("fastcgi_param")
+	assert.Len(t, directives, 1)
+	directives[0].SetValues([]string{"SCRIPT_FILENAME", "$realpath_root$fastcgi_script_name"})
+
+	externalContent := "server {\n    server_name example.com www.example.com;\n}\n"
+	fsys.MapFS[exampleFileName] = &fstest.MapFile{Data: []byte(externalContent)}
+
+	err = config.Dump()
+
+	var conflictErr *ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []string{"/" + exampleFileName}, conflictErr.FilePaths)
+	assert.Equal(t, externalContent, string(fsys.MapFS[exampleFileName].Data))
+	assert.NotContains(t, string(fsys.MapFS["etc/nginx/snippets/fastcgi.conf"].Data), "$realpath_root")
+
+	err = config.DumpWithOptions(DumpOptions{Force: true})
+	assert.Nil(t, err)
+	assert.Contains(t, string(fsys.MapFS[exampleFileName].Data), "listen 80;")
+
+	// the written content becomes the new reference
+	for _, serverBlock := range config.FindServerBlocks() {
+		serverBlock.AddDirective(NewDirective("listen", []string{"443"}), false, true)
+	}
+
+	err = config.Dump()
+	assert.Nil(t, err)
+}
+
+func TestDumpNewFileConflict(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	configFile, err := config.AddConfigFile("/etc/nginx/conf.d/new.conf")
+	assert.Nil(t, err)
+
+	fsys.MapFS["etc/nginx/conf.d/new.conf"] = &fstest.MapFile{Data: []byte("gzip on;\n")}
+
+	err = configFile.Dump()
+
+	var conflictErr *ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []string{"/etc/nginx/conf.d/new.conf"}, conflictErr.FilePaths)
+}
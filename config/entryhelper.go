@@ -1,6 +1,9 @@
 package config
 
-import "github.com/r2dtools/gonginxconf/internal/rawparser"
+import (
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"golang.org/x/exp/slices"
+)
 
 type entryContainer interface {
 	GetEntries() []*rawparser.Entry
@@ -50,3 +53,34 @@ func findEntryCommentIndexesToDelete(entries []*rawparser.Entry, index int) []in
 
 	return indexesToDelete
 }
+
+// addEntries inserts copies of the entries, so the same fragment can be added several times
+func addEntries(c entryContainer, entries []*rawparser.Entry, toBegining bool) {
+	newEntries := rawparser.CloneEntries(entries)
+
+	if len(newEntries) == 0 {
+		return
+	}
+
+	containerEntries := c.GetEntries()
+
+	if toBegining {
+		lastEntry := newEntries[len(newEntries)-1]
+
+		if len(lastEntry.EndNewLines) == 0 {
+			lastEntry.EndNewLines = []string{"\n"}
+		}
+
+		containerEntries = slices.Insert(containerEntries, 0, newEntries...)
+	} else {
+		firstEntry := newEntries[0]
+
+		if len(containerEntries) > 0 && len(containerEntries[len(containerEntries)-1].EndNewLines) == 0 && len(firstEntry.StartNewLines) == 0 {
+			firstEntry.StartNewLines = []string{"\n"}
+		}
+
+		containerEntries = append(containerEntries, newEntries...)
+	}
+
+	setEntries(c, containerEntries)
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RemoveConfigFile removes the file from the configuration, the file is deleted on disk on Dump.
+// Include directives referring to the file by its exact path are deleted as well,
+// include directives with a mask are kept.
+func (c *Config) RemoveConfigFile(filePath string) error {
+	filePath, err := c.getConfigFilePath(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	delete(c.parsedFiles, filePath)
+	delete(c.modifiedFiles, filePath)
+
+	if c.newFiles[filePath] {
+		delete(c.newFiles, filePath)
+	} else if originalPath, ok := c.renamedFiles[filePath]; ok {
+		delete(c.renamedFiles, filePath)
+		c.addRemovedFile(originalPath)
+	} else {
+		c.addRemovedFile(filePath)
+	}
+
+	var edges []IncludeEdge
+
+	for _, edge := range c.includeEdges {
+		if edge.From == filePath {
+			continue
+		}
+
+		if edge.To == filePath {
+			if c.isLiteralInclude(edge.Directive, filePath) {
+				deleteDirective(edge.Directive.container, edge.Directive)
+				c.markModified(edge.From)
+				c.dropEdits(edge.From)
+			}
+
+			continue
+		}
+
+		edges = append(edges, edge)
+	}
+
+	c.includeEdges = edges
+	c.dropUnreachableFiles()
+
+	return nil
+}
+
+// RenameConfigFile moves the file to the new path, the file is renamed on disk on Dump.
+// Include directives referring to the file by its exact path are updated, files included
+// with a mask stay included only if the new path matches the mask.
+// Objects found before the rename keep the old file path in the FilePath field.
+// Relative paths are resolved against the server root.
+func (c *Config) RenameConfigFile(oldFilePath, newFilePath string) (*ConfigFile, error) {
+	oldFilePath, err := c.getConfigFilePath(oldFilePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	newFilePath = c.getAbsPath(newFilePath)
+
+	if _, ok := c.parsedFiles[newFilePath]; ok {
+		return nil, fmt.Errorf("file %s already exists", newFilePath)
+	}
+
+	if _, err := c.fileSystem.Stat(newFilePath); err == nil && !c.removedFiles[newFilePath] {
+		return nil, fmt.Errorf("file %s already exists", newFilePath)
+	}
+
+	tree := c.parsedFiles[oldFilePath]
+	c.parsedFiles[newFilePath] = tree
+	delete(c.parsedFiles, oldFilePath)
+
+	if c.modifiedFiles[oldFilePath] {
+		delete(c.modifiedFiles, oldFilePath)
+		c.markModified(newFilePath)
+	}
+
+	if c.newFiles[oldFilePath] {
+		delete(c.newFiles, oldFilePath)
+		c.newFiles[newFilePath] = true
+	} else {
+		originalPath, ok := c.renamedFiles[oldFilePath]
+
+		if !ok {
+			originalPath = oldFilePath
+		}
+
+		if c.renamedFiles == nil {
+			c.renamedFiles = make(map[string]string)
+		}
+
+		delete(c.renamedFiles, oldFilePath)
+
+		if originalPath != newFilePath {
+			c.renamedFiles[newFilePath] = originalPath
+		}
+	}
+
+	if c.movedFiles == nil {
+		c.movedFiles = make(map[string]string)
+	}
+
+	c.movedFiles[oldFilePath] = newFilePath
+	delete(c.movedFiles, newFilePath)
+
+	var edges []IncludeEdge
+
+	for _, edge := range c.includeEdges {
+		if edge.From == oldFilePath {
+			edge.From = newFilePath
+			edge.Directive.filePath = newFilePath
+		}
+
+		if edge.To == oldFilePath {
+			if c.isLiteralInclude(edge.Directive, oldFilePath) {
+				edge.Directive.SetValue(c.getIncludePath(edge.Directive.GetFirstValue(), newFilePath))
+			} else if matched, _ := filepath.Match(c.getAbsPath(edge.Directive.GetFirstValue()), newFilePath); !matched {
+				continue
+			}
+
+			edge.To = newFilePath
+		}
+
+		edges = append(edges, edge)
+	}
+
+	c.includeEdges = edges
+	c.dropUnreachableFiles()
+
+	return &ConfigFile{
+		FilePath:   newFilePath,
+		configFile: tree,
+		config:     c,
+	}, nil
+}
+
+// dropUnreachableFiles forgets files nginx would not load anymore, e.g. snippets of a removed file.
+// Files with pending changes are kept to be written by Dump.
+func (c *Config) dropUnreachableFiles() {
+	reachable := make(map[string]bool)
+
+	for _, filePath := range c.LoadOrder() {
+		reachable[filePath] = true
+	}
+
+	for filePath := range c.parsedFiles {
+		if reachable[filePath] || c.modifiedFiles[filePath] || c.newFiles[filePath] {
+			continue
+		}
+
+		if _, ok := c.renamedFiles[filePath]; ok {
+			continue
+		}
+
+		delete(c.parsedFiles, filePath)
+		delete(c.fileStates, filePath)
+	}
+
+	var edges []IncludeEdge
+
+	for _, edge := range c.includeEdges {
+		if _, ok := c.parsedFiles[edge.From]; ok {
+			edges = append(edges, edge)
+		}
+	}
+
+	c.includeEdges = edges
+}
+
+func (c *Config) getConfigFilePath(filePath string) (string, error) {
+	filePath = c.getAbsPath(filePath)
+
+	if _, ok := c.parsedFiles[filePath]; !ok {
+		return "", fmt.Errorf("file %s is not a part of the configuration", filePath)
+	}
+
+	return filePath, nil
+}
+
+func (c *Config) addRemovedFile(filePath string) {
+	if c.removedFiles == nil {
+		c.removedFiles = make(map[string]bool)
+	}
+
+	c.removedFiles[filePath] = true
+}
+
+// isLiteralInclude checks that the include directive refers to the file without a mask
+func (c *Config) isLiteralInclude(directive Directive, filePath string) bool {
+	includePath := directive.GetFirstValue()
+
+	return !strings.ContainsAny(includePath, "*?[") && c.getAbsPath(includePath) == filePath
+}
+
+// getIncludePath keeps include paths relative to the server root if they were relative before
+func (c *Config) getIncludePath(previousPath, filePath string) string {
+	if filepath.IsAbs(previousPath) {
+		return filePath
+	}
+
+	if relPath, err := filepath.Rel(c.serverRoot, filePath); err == nil && !strings.HasPrefix(relPath, "..") {
+		return relPath
+	}
+
+	return filePath
+}
+
+// getCurrentPath follows renames of the file, objects found before a rename keep the old path
+func (c *Config) getCurrentPath(filePath string) string {
+	for index := 0; index < len(c.movedFiles); index++ {
+		newFilePath, ok := c.movedFiles[filePath]
+
+		if !ok {
+			break
+		}
+
+		filePath = newFilePath
+	}
+
+	return filePath
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveConfigFile(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	err = config.RemoveConfigFile("/etc/nginx/snippets/fastcgi.conf")
+	assert.Nil(t, err)
+	assert.Nil(t, config.GetConfigFile("fastcgi.conf"))
+	assert.Empty(t, config.FindDirectives("fastcgi_param"))
+	assert.Len(t, config.FindDirectives("include"), 1)
+
+	err = config.RemoveConfigFile("/etc/nginx/snippets/fastcgi.conf")
+	assert.NotNil(t, err)
+
+	diffs, err := config.Diff()
+	assert.Nil(t, err)
+	assert.Len(t, diffs, 2)
+	assert.Equal(t, "/etc/nginx/snippets/fastcgi.conf", diffs[1].FilePath)
+	assert.True(t, diffs[1].IsRemoved)
+
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.NotContains(t, fsys.MapFS, "etc/nginx/snippets/fastcgi.conf")
+	assert.NotContains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data), "include")
+}
+
+func TestRenameConfigFile(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	configFile, err := config.RenameConfigFile("snippets/fastcgi.conf", "snippets/php.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, "/etc/nginx/snippets/php.conf", configFile.FilePath)
+	assert.Len(t, configFile.IncludedBy(), 1)
+
+	// the file is no longer matched by the include mask
+	_, err = config.RenameConfigFile("/etc/nginx/sites-enabled/example.com.conf", "/etc/nginx/sites-enabled/example.com.conf.disabled")
+	assert.Nil(t, err)
+	httpBlocks := config.FindHttpBlocks()
+	assert.Len(t, httpBlocks, 1)
+	assert.Empty(t, httpBlocks[0].FindServerBlocks())
+	assert.Empty(t, config.FindServerBlocks())
+	assert.Empty(t, config.FindServerBlocksByServerName("example.com"))
+	assert.NotContains(t, config.LoadOrder(), "/etc/nginx/sites-enabled/example.com.conf.disabled")
+	// the snippet is included by the disabled file only, it is kept to be renamed on Dump
+	assert.Empty(t, config.FindDirectives("fastcgi_param"))
+
+	_, err = config.RenameConfigFile("/etc/nginx/nginx.conf", "/etc/nginx/snippets/php.conf")
+	assert.NotNil(t, err)
+
+	diffs, err := config.Diff()
+	assert.Nil(t, err)
+	assert.Len(t, diffs, 2)
+	assert.Equal(t, "/etc/nginx/sites-enabled/example.com.conf", diffs[0].RenamedFrom)
+	assert.Contains(t, diffs[0].Diff, "+        include snippets/php.conf;")
+
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.NotContains(t, fsys.MapFS, "etc/nginx/snippets/fastcgi.conf")
+	assert.NotContains(t, fsys.MapFS, "etc/nginx/sites-enabled/example.com.conf")
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/snippets/php.conf"].Data), "fastcgi_param")
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf.disabled"].Data), "include snippets/php.conf;")
+
+	config, err = GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	assert.Empty(t, config.FindServerBlocks())
+}
+
+func TestRenameConfigFileRollback(t *testing.T) {
+	fsys := failingMapFS{
+		writableMapFS:   writableMapFS{MapFS: getTestMapFS()},
+		failingFileName: "etc/nginx/nginx.conf",
+	}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	_, err = config.RenameConfigFile("/etc/nginx/snippets/fastcgi.conf", "/etc/nginx/snippets/php.conf")
+	assert.Nil(t, err)
+	config.FindHttpBlocks()[0].SetComments([]string{"http"})
+
+	err = config.Dump()
+	assert.True(t, errors.Is(err, errTestWriteFailed))
+	assert.Contains(t, fsys.MapFS, "etc/nginx/snippets/fastcgi.conf")
+	assert.NotContains(t, fsys.MapFS, "etc/nginx/snippets/php.conf")
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data), "include snippets/fastcgi.conf;")
+}
+
+func TestRemoveIncludingConfigFile(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	err = config.RemoveConfigFile("sites-enabled/example.com.conf")
+	assert.Nil(t, err)
+	assert.Empty(t, config.FindDirectives("fastcgi_param"))
+	assert.Equal(t, []string{"/etc/nginx/nginx.conf"}, config.LoadOrder())
+	assert.Nil(t, config.GetConfigFile("fastcgi.conf"))
+
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.NotContains(t, fsys.MapFS, "etc/nginx/sites-enabled/example.com.conf")
+	assert.Contains(t, fsys.MapFS, "etc/nginx/snippets/fastcgi.conf")
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// fileState is a snapshot of a file taken when it was parsed or written
+type fileState struct {
+	// content is the base for merging in-memory changes with changes made on disk
+	content []byte
+	hash    [sha256.Size]byte
+	size    int64
+	modTime time.Time
+}
+
+// ConflictError is returned by Dump when files were changed on disk after they had been parsed
+type ConflictError struct {
+	FilePaths []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("files were modified on disk after they had been parsed: %s", strings.Join(e.FilePaths, ", "))
+}
+
+func (c *Config) recordFileState(filePath string, content []byte) {
+	state := fileState{
+		content: content,
+		hash:    sha256.Sum256(content),
+		size:    int64(len(content)),
+	}
+
+	if stat, err := c.fileSystem.Stat(filePath); err == nil {
+		state.modTime = stat.ModTime()
+	}
+
+	if c.fileStates == nil {
+		c.fileStates = make(map[string]fileState)
+	}
+
+	c.fileStates[filePath] = state
+}
+
+// isChangedOnDisk compares the current file content with the recorded one.
+// Files that are not tracked yet must not exist on disk.
+func (c *Config) isChangedOnDisk(filePath string, exists bool, content []byte) bool {
+	state, ok := c.fileStates[filePath]
+
+	if !ok {
+		return c.newFiles[filePath] && exists
+	}
+
+	return !exists || state.hash != sha256.Sum256(content)
+}
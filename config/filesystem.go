@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrReadOnlyFileSystem = errors.New("file system is read-only")
+var ErrSymlinkNotSupported = errors.New("file system does not support symbolic links")
+
+// WriteFileFS is implemented by file systems that allow Dump to persist configuration files
+type WriteFileFS interface {
+	fs.FS
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// RemoveFS is implemented by file systems that allow to delete files,
+// it is required to roll back files created by a failed Dump
+type RemoveFS interface {
+	fs.FS
+	Remove(name string) error
+}
+
+// RenameFS is implemented by file systems that can move files. Files of other writable
+// file systems are moved by writing them to the new path and removing the old one.
+type RenameFS interface {
+	fs.FS
+	Rename(oldName, newName string) error
+}
+
+// SymlinkFS is implemented by file systems that support symbolic links,
+// it is required to enable and disable sites
+type SymlinkFS interface {
+	fs.FS
+	Lstat(name string) (fs.FileInfo, error)
+	ReadLink(name string) (string, error)
+	Symlink(oldName, newName string) error
+}
+
+// fileSystem is used for all file access of a Config. Names are absolute paths.
+type fileSystem interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	Glob(pattern string) ([]string, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldName, newName string) error
+	Lstat(name string) (fs.FileInfo, error)
+	ReadLink(name string) (string, error)
+	Symlink(oldName, newName string) error
+	// Abs resolves paths of new files passed to AddConfigFile
+	Abs(name string) (string, error)
+}
+
+type osFileSystem struct{}
+
+func (osFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFileSystem) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+func (osFileSystem) Rename(oldName, newName string) error {
+	return os.Rename(oldName, newName)
+}
+
+func (osFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (osFileSystem) ReadLink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (osFileSystem) Symlink(oldName, newName string) error {
+	return os.Symlink(oldName, newName)
+}
+
+func (osFileSystem) Abs(name string) (string, error) {
+	return filepath.Abs(name)
+}
+
+// WriteFile replaces the file atomically: data is written to a temporary file in the same directory
+// which is synced and renamed over the original. Mode and owner of an existing file are preserved,
+// perm is used for new files only. Symlinks are followed, so the link itself is kept.
+func (osFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if target, err := filepath.EvalSymlinks(name); err == nil {
+		name = target
+	}
+
+	stat, err := os.Stat(name)
+
+	if err == nil {
+		perm = stat.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+
+	if err != nil {
+		return err
+	}
+
+	tmpFileName := tmpFile.Name()
+	err = writeAndSync(tmpFile, data)
+
+	if err == nil {
+		err = os.Chmod(tmpFileName, perm)
+	}
+
+	if err == nil && stat != nil {
+		// changing owner is allowed to privileged users only, so it is done on the best effort basis
+		_ = chown(tmpFileName, stat)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFileName, name)
+	}
+
+	if err != nil {
+		os.Remove(tmpFileName)
+
+		return err
+	}
+
+	return syncDir(filepath.Dir(name))
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func writeAndSync(file *os.File, data []byte) error {
+	_, err := file.Write(data)
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// ioFileSystem maps absolute paths to an fs.FS, the root of the fs.FS is treated as "/"
+type ioFileSystem struct {
+	fsys fs.FS
+}
+
+func (f ioFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.fsys, f.getFsName(name))
+}
+
+func (f ioFileSystem) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, f.getFsName(name))
+}
+
+func (f ioFileSystem) Glob(pattern string) ([]string, error) {
+	names, err := fs.Glob(f.fsys, f.getFsName(pattern))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for index, name := range names {
+		names[index] = "/" + name
+	}
+
+	return names, nil
+}
+
+func (f ioFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	writeFS, ok := f.fsys.(WriteFileFS)
+
+	if !ok {
+		return &fs.PathError{Op: "write", Path: name, Err: ErrReadOnlyFileSystem}
+	}
+
+	return writeFS.WriteFile(f.getFsName(name), data, perm)
+}
+
+func (f ioFileSystem) Remove(name string) error {
+	removeFS, ok := f.fsys.(RemoveFS)
+
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnlyFileSystem}
+	}
+
+	return removeFS.Remove(f.getFsName(name))
+}
+
+func (f ioFileSystem) Rename(oldName, newName string) error {
+	if renameFS, ok := f.fsys.(RenameFS); ok {
+		return renameFS.Rename(f.getFsName(oldName), f.getFsName(newName))
+	}
+
+	stat, err := f.Stat(oldName)
+
+	if err != nil {
+		return err
+	}
+
+	content, err := f.ReadFile(oldName)
+
+	if err != nil {
+		return err
+	}
+
+	if err := f.WriteFile(newName, content, stat.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return f.Remove(oldName)
+}
+
+// Lstat falls back to Stat if the file system does not support symbolic links
+func (f ioFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	if symlinkFS, ok := f.fsys.(SymlinkFS); ok {
+		return symlinkFS.Lstat(f.getFsName(name))
+	}
+
+	return f.Stat(name)
+}
+
+func (f ioFileSystem) ReadLink(name string) (string, error) {
+	if symlinkFS, ok := f.fsys.(SymlinkFS); ok {
+		return symlinkFS.ReadLink(f.getFsName(name))
+	}
+
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: ErrSymlinkNotSupported}
+}
+
+func (f ioFileSystem) Symlink(oldName, newName string) error {
+	if symlinkFS, ok := f.fsys.(SymlinkFS); ok {
+		return symlinkFS.Symlink(oldName, f.getFsName(newName))
+	}
+
+	return &fs.PathError{Op: "symlink", Path: newName, Err: ErrSymlinkNotSupported}
+}
+
+// Abs treats relative paths as relative to the root of the fs.FS
+func (f ioFileSystem) Abs(name string) (string, error) {
+	return path.Join("/", filepath.ToSlash(name)), nil
+}
+
+func (f ioFileSystem) getFsName(name string) string {
+	name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
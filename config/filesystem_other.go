@@ -0,0 +1,13 @@
+//go:build !unix
+
+package config
+
+import "io/fs"
+
+func chown(name string, info fs.FileInfo) error {
+	return nil
+}
+
+func syncDir(name string) error {
+	return nil
+}
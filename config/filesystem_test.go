@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type writableMapFS struct {
+	fstest.MapFS
+}
+
+func (m writableMapFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.MapFS[name] = &fstest.MapFile{Data: data, Mode: perm}
+
+	return nil
+}
+
+func (m writableMapFS) Remove(name string) error {
+	if _, ok := m.MapFS[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+
+	delete(m.MapFS, name)
+
+	return nil
+}
+
+func TestGetConfigFromFS(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	serverBlocks := config.FindServerBlocksByServerName("example.com")
+	assert.Len(t, serverBlocks, 1)
+	assert.Equal(t, "/etc/nginx/sites-enabled/example.com.conf", serverBlocks[0].FilePath)
+
+	directives := serverBlocks[0].FindDirectives("fastcgi_param")
+	assert.Len(t, directives, 1)
+	assert.Equal(t, "/etc/nginx/snippets/fastcgi.conf", directives[0].GetPosition().FilePath)
+
+	serverBlocks[0].AddDirective(NewDirective("root", []string{"/var/www/example.com"}), false, true)
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data), "root /var/www/example.com;")
+}
+
+func TestGetConfigFromReadOnlyFS(t *testing.T) {
+	config, err := GetConfigFromFS(getTestMapFS(), "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	serverBlocks := config.FindServerBlocks()
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].SetComments([]string{"example.com"})
+
+	err = config.Dump()
+	assert.True(t, errors.Is(err, ErrReadOnlyFileSystem))
+
+	_, err = GetConfigFromFS(getTestMapFS(), "/etc/nginx", "missing.conf", false)
+	assert.NotNil(t, err)
+}
+
+func getTestMapFS() fstest.MapFS {
+	return fstest.MapFS{
+		"etc/nginx/nginx.conf": &fstest.MapFile{
+			Data: []byte("http {\n    include /etc/nginx/sites-enabled/*.conf;\n}\n"),
+		},
+		"etc/nginx/sites-enabled/example.com.conf": &fstest.MapFile{
+			Data: []byte("server {\n    server_name example.com;\n    location ~ \\.php$ {\n        include snippets/fastcgi.conf;\n    }\n}\n"),
+		},
+		"etc/nginx/snippets/fastcgi.conf": &fstest.MapFile{
+			Data: []byte("fastcgi_param SCRIPT_FILENAME $document_root$fastcgi_script_name;\n"),
+		},
+	}
+}
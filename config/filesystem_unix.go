@@ -0,0 +1,31 @@
+//go:build unix
+
+package config
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+func chown(name string, info fs.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	if !ok || (int(stat.Uid) == os.Getuid() && int(stat.Gid) == os.Getgid()) {
+		return nil
+	}
+
+	return os.Chown(name, int(stat.Uid), int(stat.Gid))
+}
+
+func syncDir(name string) error {
+	dir, err := os.Open(name)
+
+	if err != nil {
+		return err
+	}
+
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/r2dtools/gonginxconf/internal/rawdumper"
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"golang.org/x/exp/slices"
+)
+
+var ErrNothingToUndo = errors.New("there are no edits to undo")
+var ErrNothingToRedo = errors.New("there are no undone edits to redo")
+var ErrEditOutdated = errors.New("file of the edit was reloaded or removed")
+
+type EditType string
+
+const (
+	EditAddDirective    EditType = "add_directive"
+	EditDeleteDirective EditType = "delete_directive"
+	EditSetValues       EditType = "set_values"
+	EditSetComments     EditType = "set_comments"
+	EditAddBlock        EditType = "add_block"
+	EditDeleteBlock     EditType = "delete_block"
+	EditSetParameters   EditType = "set_parameters"
+	EditAddFragment     EditType = "add_fragment"
+)
+
+// Edit is a change of a parsed file recorded in the edit log of a Config
+type Edit struct {
+	Type     EditType
+	FilePath string
+	// Target is the path of the changed directive or block in the file, e.g. "http > server[example.com] > listen"
+	Target string
+	// Before and After are the text of the changed block or file before and after the edit
+	Before string
+	After  string
+
+	tree     *rawparser.Config
+	before   *rawparser.Snapshot
+	after    *rawparser.Snapshot
+	modified bool
+}
+
+// Edits returns the edits that can be undone, the most recent one is the last
+func (c *Config) Edits() []Edit {
+	return append([]Edit(nil), c.edits...)
+}
+
+// UndoneEdits returns the edits that can be redone, the next one to redo is the last
+func (c *Config) UndoneEdits() []Edit {
+	return append([]Edit(nil), c.undoneEdits...)
+}
+
+// Undo reverts the most recent edit. Objects found before the edit stay valid,
+// objects created by the edit are detached until it is redone.
+func (c *Config) Undo() (Edit, error) {
+	if len(c.edits) == 0 {
+		return Edit{}, ErrNothingToUndo
+	}
+
+	edit := c.edits[len(c.edits)-1]
+
+	if !c.isEditActual(edit) {
+		return edit, ErrEditOutdated
+	}
+
+	edit.before.Restore()
+	c.edits = c.edits[:len(c.edits)-1]
+	c.undoneEdits = append(c.undoneEdits, edit)
+
+	// the file matches its content on disk again unless it was dumped after the edit
+	filePath := c.getCurrentPath(edit.FilePath)
+
+	if !edit.modified && c.modifiedFiles[filePath] {
+		delete(c.modifiedFiles, filePath)
+	} else {
+		c.markModified(filePath)
+	}
+
+	return edit, nil
+}
+
+// Redo applies the most recently undone edit again
+func (c *Config) Redo() (Edit, error) {
+	if len(c.undoneEdits) == 0 {
+		return Edit{}, ErrNothingToRedo
+	}
+
+	edit := c.undoneEdits[len(c.undoneEdits)-1]
+
+	if !c.isEditActual(edit) {
+		return edit, ErrEditOutdated
+	}
+
+	edit.after.Restore()
+	c.undoneEdits = c.undoneEdits[:len(c.undoneEdits)-1]
+	c.edits = append(c.edits, edit)
+	c.markModified(edit.FilePath)
+
+	return edit, nil
+}
+
+// SetHistoryLimit enables the edit log used by Undo and Redo, at most limit recent edits are kept.
+// The edit log is disabled by default, a limit of 0 disables it and forgets recorded edits.
+func (c *Config) SetHistoryLimit(limit int) {
+	c.historyLimit = max(limit, 0)
+
+	if len(c.edits) > c.historyLimit {
+		c.edits = slices.Clone(c.edits[len(c.edits)-c.historyLimit:])
+	}
+
+	if len(c.undoneEdits) > c.historyLimit {
+		c.undoneEdits = slices.Clone(c.undoneEdits[len(c.undoneEdits)-c.historyLimit:])
+	}
+}
+
+// edit applies the change and records it in the edit log if it is enabled. Changes of objects that do not
+// belong to a parsed file, e.g. fragments created with ParseString, are not recorded.
+// Only the changed container is kept in snapshots of the edit.
+func (c *Config) edit(editType EditType, filePath string, container entryContainer, target string, change func()) {
+	if c == nil {
+		change()
+
+		return
+	}
+
+	filePath = c.getCurrentPath(filePath)
+
+	if c.historyLimit == 0 {
+		change()
+		c.markModified(filePath)
+
+		return
+	}
+
+	tree := c.parsedFiles[filePath]
+	path, ok := getContainerPath(tree, container)
+
+	if tree == nil || !ok {
+		change()
+		c.markModified(filePath)
+
+		return
+	}
+
+	if target != "" {
+		path = append(path, target)
+	}
+
+	edit := Edit{
+		Type:     editType,
+		FilePath: filePath,
+		Target:   strings.Join(path, " > "),
+		Before:   dumpContainer(container),
+		tree:     tree,
+		before:   snapshotContainer(container),
+		modified: c.modifiedFiles[filePath],
+	}
+
+	change()
+	c.markModified(filePath)
+
+	edit.After = dumpContainer(container)
+	edit.after = snapshotContainer(container)
+	c.edits = append(c.edits, edit)
+	c.undoneEdits = nil
+
+	if len(c.edits) > c.historyLimit {
+		c.edits = slices.Delete(c.edits, 0, len(c.edits)-c.historyLimit)
+	}
+}
+
+// dropEdits forgets edits of the file when it is changed bypassing the edit log
+func (c *Config) dropEdits(filePath string) {
+	filePath = c.getCurrentPath(filePath)
+	filter := func(edits []Edit) []Edit {
+		var kept []Edit
+
+		for _, edit := range edits {
+			if c.getCurrentPath(edit.FilePath) != filePath {
+				kept = append(kept, edit)
+			}
+		}
+
+		return kept
+	}
+
+	c.edits = filter(c.edits)
+	c.undoneEdits = filter(c.undoneEdits)
+}
+
+func (c *Config) isEditActual(edit Edit) bool {
+	tree, ok := c.parsedFiles[c.getCurrentPath(edit.FilePath)]
+
+	return ok && tree == edit.tree
+}
+
+// getContainerPath returns context names of blocks from the root of the tree to the container
+func getContainerPath(tree *rawparser.Config, container entryContainer) ([]string, bool) {
+	if tree == nil {
+		return nil, false
+	}
+
+	if rawContainer, ok := container.(*rawparser.Config); ok {
+		return nil, rawContainer == tree
+	}
+
+	return findContainerPath(tree.GetEntries(), container)
+}
+
+func findContainerPath(entries []*rawparser.Entry, container entryContainer) ([]string, bool) {
+	for _, entry := range entries {
+		block := entry.BlockDirective
+
+		if block == nil {
+			continue
+		}
+
+		if block == container {
+			return []string{getBlockContextName(block)}, true
+		}
+
+		if path, ok := findContainerPath(block.GetEntries(), container); ok {
+			return append([]string{getBlockContextName(block)}, path...), true
+		}
+	}
+
+	return nil, false
+}
+
+func snapshotContainer(container entryContainer) *rawparser.Snapshot {
+	snapshot := rawparser.NewSnapshot()
+
+	switch rawContainer := container.(type) {
+	case *rawparser.Config:
+		snapshot.Add(rawContainer)
+	case *rawparser.BlockDirective:
+		snapshot.AddBlock(rawContainer)
+	}
+
+	return snapshot
+}
+
+func dumpContainer(container entryContainer) string {
+	switch rawContainer := container.(type) {
+	case *rawparser.Config:
+		content, _ := dumpTree(rawContainer)
+
+		return content
+	case *rawparser.BlockDirective:
+		dumper := &rawdumper.RawDumper{}
+
+		return dumper.DumpEntry(&rawparser.Entry{BlockDirective: rawContainer})
+	}
+
+	return ""
+}
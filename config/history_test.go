@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoRedo(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	config.SetHistoryLimit(10)
+
+	serverBlock := config.FindServerBlocksByServerName("example.com")[0]
+	serverName := config.FindDirectives("server_name")[0]
+
+	serverBlock.AddDirective(NewDirective("listen", []string{"80"}), true, true)
+	serverName.SetValues([]string{"example.com", "www.example.com"})
+	locationBlock := serverBlock.AddLocationBlock("", "/api", false)
+	locationBlock.AddDirective(NewDirective("proxy_pass", []string{"http://backend"}), false, true)
+
+	edits := config.Edits()
+	assert.Len(t, edits, 4)
+	assert.Equal(t, EditAddDirective, edits[0].Type)
+	assert.Equal(t, "/etc/nginx/sites-enabled/example.com.conf", edits[0].FilePath)
+	assert.Equal(t, "server[example.com] > listen", edits[0].Target)
+	assert.NotContains(t, edits[0].Before, "listen 80;")
+	assert.Contains(t, edits[0].After, "listen 80;")
+	assert.Equal(t, EditSetValues, edits[1].Type)
+	assert.Equal(t, "server[example.com] > server_name", edits[1].Target)
+	assert.Equal(t, EditAddBlock, edits[2].Type)
+	assert.Equal(t, "server[example.com www.example.com] > location[/api]", edits[2].Target)
+	assert.Equal(t, "server[example.com www.example.com] > location[/api] > proxy_pass", edits[3].Target)
+
+	edit, err := config.Undo()
+	assert.Nil(t, err)
+	assert.Equal(t, EditAddDirective, edit.Type)
+	assert.Empty(t, locationBlock.FindDirectives("proxy_pass"))
+
+	_, err = config.Undo()
+	assert.Nil(t, err)
+	assert.Len(t, serverBlock.FindLocationBlocks(), 1)
+
+	_, err = config.Undo()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"example.com"}, serverName.GetValues())
+	assert.Len(t, config.UndoneEdits(), 3)
+
+	edit, err = config.Redo()
+	assert.Nil(t, err)
+	assert.Equal(t, EditSetValues, edit.Type)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, serverName.GetValues())
+
+	edit, err = config.Redo()
+	assert.Nil(t, err)
+	assert.Equal(t, EditAddBlock, edit.Type)
+	assert.Len(t, serverBlock.FindLocationBlocks(), 2)
+
+	// a new edit discards edits that can be redone
+	serverBlock.DeleteDirectiveByName("listen")
+	assert.Empty(t, config.UndoneEdits())
+	_, err = config.Redo()
+	assert.True(t, errors.Is(err, ErrNothingToRedo))
+
+	for len(config.Edits()) != 0 {
+		_, err = config.Undo()
+		assert.Nil(t, err)
+	}
+
+	_, err = config.Undo()
+	assert.True(t, errors.Is(err, ErrNothingToUndo))
+	assert.Empty(t, config.ModifiedFiles())
+
+	diffs, err := config.Diff()
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestUndoAfterDump(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	config.SetHistoryLimit(10)
+
+	config.FindDirectives("server_name")[0].SetValue("changed.example.com")
+	err = config.Dump()
+	assert.Nil(t, err)
+
+	_, err = config.Undo()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/etc/nginx/sites-enabled/example.com.conf"}, config.ModifiedFiles())
+
+	_, err = config.Redo()
+	assert.Nil(t, err)
+	err = config.Dump()
+	assert.Nil(t, err)
+
+	fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data = []byte("server {\n    server_name other.example.com;\n}\n")
+	_, err = config.Reload()
+	assert.Nil(t, err)
+
+	_, err = config.Undo()
+	assert.True(t, errors.Is(err, ErrEditOutdated))
+}
+
+func TestHistoryLimit(t *testing.T) {
+	config, err := GetConfigFromFS(getTestMapFS(), "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	serverName := config.FindDirectives("server_name")[0]
+
+	// the edit log is disabled by default
+	serverName.SetValue("first.example.com")
+	assert.Empty(t, config.Edits())
+	_, err = config.Undo()
+	assert.True(t, errors.Is(err, ErrNothingToUndo))
+
+	config.SetHistoryLimit(2)
+	serverName.SetValue("second.example.com")
+	serverName.SetValue("third.example.com")
+	serverName.SetValue("fourth.example.com")
+
+	edits := config.Edits()
+	assert.Len(t, edits, 2)
+	assert.Contains(t, edits[0].Before, "second.example.com")
+	assert.Contains(t, edits[1].After, "fourth.example.com")
+
+	_, err = config.Undo()
+	assert.Nil(t, err)
+	_, err = config.Undo()
+	assert.Nil(t, err)
+	assert.Equal(t, "second.example.com", serverName.GetFirstValue())
+	_, err = config.Undo()
+	assert.True(t, errors.Is(err, ErrNothingToUndo))
+
+	config.SetHistoryLimit(0)
+	assert.Empty(t, config.UndoneEdits())
+}
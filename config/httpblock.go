@@ -45,9 +45,9 @@ func (b *HttpBlock) AddServerBlock() ServerBlock {
 }
 
 func (b *HttpBlock) DeleteServerBlock(serverBlock ServerBlock) {
-	deleteBlock(b.rawBlock, serverBlock.Block)
+	b.deleteBlock(serverBlock.Block)
 }
 
 func (b *HttpBlock) DeleteUpstreamBlock(upsstreamBlock UpstreamBlock) {
-	deleteBlock(b.rawBlock, upsstreamBlock.Block)
+	b.deleteBlock(upsstreamBlock.Block)
 }
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"sort"
+
+	"golang.org/x/exp/maps"
+)
+
+// IncludeEdge describes an include directive that pulled a file into another one
+type IncludeEdge struct {
+	// From is the file containing the include directive
+	From string
+	// To is the included file, include directives with a mask produce an edge per matched file
+	To        string
+	Directive Directive
+	Position  Position
+}
+
+type IncludeGraph struct {
+	// Nodes contains paths of all parsed files
+	Nodes []string
+	Edges []IncludeEdge
+}
+
+func (c *Config) IncludeGraph() IncludeGraph {
+	nodes := maps.Keys(c.parsedFiles)
+	sort.Strings(nodes)
+
+	return IncludeGraph{
+		Nodes: nodes,
+		Edges: append([]IncludeEdge{}, c.includeEdges...),
+	}
+}
+
+// Includes returns include edges going out of the file
+func (c *ConfigFile) Includes() []IncludeEdge {
+	var edges []IncludeEdge
+
+	for _, edge := range c.config.includeEdges {
+		if edge.From == c.FilePath {
+			edges = append(edges, edge)
+		}
+	}
+
+	return edges
+}
+
+// IncludedBy returns include edges pointing to the file.
+// Only the main configuration file and files loaded with ParseFile have no such edges.
+func (c *ConfigFile) IncludedBy() []IncludeEdge {
+	var edges []IncludeEdge
+
+	for _, edge := range c.config.includeEdges {
+		if edge.To == c.FilePath {
+			edges = append(edges, edge)
+		}
+	}
+
+	return edges
+}
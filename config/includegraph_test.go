@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludeGraph(t *testing.T) {
+	config := parseConfig(t)
+	graph := config.IncludeGraph()
+
+	nginxConfigFilePathAbs, err := filepath.Abs(nginxConfigFilePath)
+	assert.Nil(t, err)
+	example2ConfigFilePathAbs, err := filepath.Abs(example2ConfigFilePath)
+	assert.Nil(t, err)
+
+	assert.Contains(t, graph.Nodes, nginxConfigFilePathAbs)
+	assert.Contains(t, graph.Nodes, example2ConfigFilePathAbs)
+
+	configFile := config.GetConfigFile(example2ConfigFileName)
+	assert.NotNil(t, configFile)
+	assert.Empty(t, configFile.Includes())
+
+	edges := configFile.IncludedBy()
+	assert.Len(t, edges, 1)
+	assert.Equal(t, nginxConfigFilePathAbs, edges[0].From)
+	assert.Equal(t, example2ConfigFilePathAbs, edges[0].To)
+	assert.Equal(t, []string{"sites-enabled/*"}, edges[0].Directive.GetValues())
+	assert.Equal(t, 54, edges[0].Position.Line)
+
+	configFile = config.GetConfigFile("general.conf")
+	assert.NotNil(t, configFile)
+
+	edges = configFile.IncludedBy()
+	assert.Len(t, edges, 2)
+	// edges follow the load order: sites-enabled/* is included before the server block of nginx.conf
+	assert.Equal(t, filepath.Join(filepath.Dir(example2ConfigFilePathAbs), exampleConfigFileName), edges[0].From)
+	assert.Equal(t, 27, edges[0].Position.Line)
+	assert.Equal(t, nginxConfigFilePathAbs, edges[1].From)
+	assert.Equal(t, 91, edges[1].Position.Line)
+}
+
+func TestIncludeCycle(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":        "http {\n    include conf.d/*.conf;\n}\n",
+		"conf.d/a.conf":     "include snippets/b.conf;\n",
+		"snippets/b.conf":   "server {\n    include conf.d/a.conf;\n}\n",
+		"conf.d/other.conf": "server {\n    server_name other.com;\n}\n",
+	})
+
+	_, err := GetConfig(serverRoot, "", false)
+	assert.True(t, errors.Is(err, ErrIncludeCycle))
+	assert.Equal(
+		t,
+		"nginx.conf -> conf.d/a.conf -> snippets/b.conf -> conf.d/a.conf: include cycle detected",
+		err.Error(),
+	)
+
+	config, err := GetConfig(serverRoot, "", true)
+	assert.Nil(t, err)
+	assert.Len(t, config.Warnings(), 1)
+	assert.True(t, errors.Is(config.Warnings()[0], ErrIncludeCycle))
+	assert.Len(t, config.FindServerBlocksByServerName("other.com"), 1)
+	assert.Len(t, config.IncludeGraph().Edges, 3)
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"golang.org/x/exp/slices"
+)
+
+// includeGuard prevents following includes that form a cycle. A file included at several
+// places is followed at every place as nginx does.
+type includeGuard struct {
+	stack []string
+}
+
+func (g includeGuard) enter(filePath string) (includeGuard, bool) {
+	if slices.Contains(g.stack, filePath) {
+		return g, false
+	}
+
+	return includeGuard{
+		stack: append(slices.Clone(g.stack), filePath),
+	}, true
+}
+
+// LoadOrder returns files in the order nginx loads them: depth-first starting from the main
+// configuration file, files matched by an include mask are loaded in alphabetical order.
+// Files loaded with ParseFile follow. A file included several times is listed once at its first place.
+func (c *Config) LoadOrder() []string {
+	var files []string
+
+	c.walkFiles(func(filePath string, tree *rawparser.Config, guard includeGuard) {
+		files = append(files, filePath)
+		c.collectIncludedFiles(tree, guard, &files)
+	})
+
+	var loadOrder []string
+	listed := make(map[string]bool)
+
+	for _, filePath := range files {
+		if !listed[filePath] {
+			listed[filePath] = true
+			loadOrder = append(loadOrder, filePath)
+		}
+	}
+
+	return loadOrder
+}
+
+// walkFiles calls the callback for the main configuration file and files loaded with ParseFile
+func (c *Config) walkFiles(callback func(filePath string, tree *rawparser.Config, guard includeGuard)) {
+	for _, filePath := range c.getRootFiles() {
+		if tree, ok := c.parsedFiles[filePath]; ok {
+			callback(filePath, tree, includeGuard{stack: []string{filePath}})
+		}
+	}
+}
+
+// getRootFiles returns the main configuration file and files matched by ParseFile
+// that are not included by the files before them
+func (c *Config) getRootFiles() []string {
+	var rootFiles []string
+	reachable := make(map[string]bool)
+
+	addRootFile := func(filePath string) {
+		tree, ok := c.parsedFiles[filePath]
+
+		if !ok || reachable[filePath] {
+			return
+		}
+
+		files := []string{filePath}
+		c.collectIncludedFiles(tree, includeGuard{stack: []string{filePath}}, &files)
+
+		for _, file := range files {
+			reachable[file] = true
+		}
+
+		rootFiles = append(rootFiles, filePath)
+	}
+
+	addRootFile(c.configRoot)
+
+	for _, extraFile := range c.extraFiles {
+		extraFilePaths, err := c.findIncludedFiles(extraFile)
+
+		if err != nil {
+			continue
+		}
+
+		for _, extraFilePath := range extraFilePaths {
+			addRootFile(extraFilePath)
+		}
+	}
+
+	return rootFiles
+}
+
+func (c *Config) collectIncludedFiles(container entryContainer, guard includeGuard, files *[]string) {
+	for _, entry := range container.GetEntries() {
+		if entry.BlockDirective != nil {
+			c.collectIncludedFiles(entry.BlockDirective, guard, files)
+
+			continue
+		}
+
+		if entry.Directive == nil || strings.ToLower(entry.Directive.Identifier) != "include" {
+			continue
+		}
+
+		includeFiles, err := c.findIncludedFiles(entry.Directive.GetFirstValueStr())
+
+		if err != nil {
+			continue
+		}
+
+		for _, includePath := range includeFiles {
+			includeGuard, ok := guard.enter(includePath)
+
+			if !ok {
+				continue
+			}
+
+			*files = append(*files, includePath)
+			c.collectIncludedFiles(c.parsedFiles[includePath], includeGuard, files)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOrder(t *testing.T) {
+	config := parseConfig(t)
+	serverRoot, err := filepath.Abs("../test/nginx")
+	assert.Nil(t, err)
+
+	var loadOrder []string
+
+	for _, filePath := range config.LoadOrder() {
+		relPath, err := filepath.Rel(serverRoot, filePath)
+		assert.Nil(t, err)
+		loadOrder = append(loadOrder, relPath)
+	}
+
+	assert.Equal(t, []string{
+		"nginx.conf",
+		"mime.types",
+		"sites-enabled/default",
+		"sites-enabled/example.com.conf",
+		"nginxconfig.io/security.conf",
+		"nginxconfig.io/general.conf",
+		"nginxconfig.io/letsencrypt.conf",
+		"sites-enabled/example2.com.conf",
+		"fastcgi_params",
+	}, loadOrder)
+}
+
+func TestFindInLoadOrder(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":           "http {\n    server {\n        server_name first.com;\n    }\n    include conf.d/*.conf;\n    server {\n        server_name last.com;\n    }\n}\n",
+		"conf.d/b.conf":        "server {\n    server_name b.com;\n}\n",
+		"conf.d/a.conf":        "server {\n    server_name a.com;\n}\ninclude snippets/nested.conf;\n",
+		"snippets/nested.conf": "server {\n    server_name nested.com;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	var serverNames []string
+
+	for _, serverBlock := range config.FindServerBlocks() {
+		serverNames = append(serverNames, serverBlock.GetServerNames()...)
+	}
+
+	assert.Equal(t, []string{"first.com", "a.com", "nested.com", "b.com", "last.com"}, serverNames)
+}
+
+func TestFindInEveryInclude(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":            "http {\n    include sites-enabled/*;\n}\n",
+		"sites-enabled/a.com":   "server {\n    server_name a.com;\n    location / {\n        include snippets/fastcgi.conf;\n    }\n}\n",
+		"sites-enabled/b.com":   "server {\n    server_name b.com;\n    location / {\n        include snippets/fastcgi.conf;\n    }\n}\n",
+		"snippets/fastcgi.conf": "fastcgi_index index.php;\n",
+		"unused.conf":           "server {\n    server_name unused.com;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	// the directive is returned once at its first place
+	directives := config.FindDirectives("fastcgi_index")
+	assert.Len(t, directives, 1)
+	assert.Equal(t, "http > server[a.com] > location[/]", directives[0].GetContextPath())
+
+	// the snippet applies at both places
+	directives = config.FindDirectivesInContext("http", "fastcgi_index")
+	assert.Len(t, directives, 2)
+	assert.Equal(t, "http > server[b.com] > location[/]", directives[1].GetContextPath())
+	assert.Len(t, config.FindDirectivesInContext("http > server[b.com]", "fastcgi_index"), 1)
+
+	nodes, err := config.Select("location > fastcgi_index")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+
+	// files that nginx does not load are not searched
+	assert.Empty(t, config.FindServerBlocksByServerName("unused.com"))
+	assert.Equal(t, []string{
+		filepath.Join(serverRoot, "nginx.conf"),
+		filepath.Join(serverRoot, "sites-enabled/a.com"),
+		filepath.Join(serverRoot, "snippets/fastcgi.conf"),
+		filepath.Join(serverRoot, "sites-enabled/b.com"),
+	}, config.LoadOrder())
+
+	err = config.ParseFile("unused.conf")
+	assert.Nil(t, err)
+	assert.Len(t, config.FindServerBlocksByServerName("unused.com"), 1)
+}
+
+func TestEditSharedSnippet(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":        "http {\n    server {\n        include snippets/loc.conf;\n    }\n    server {\n        include snippets/loc.conf;\n    }\n}\n",
+		"snippets/loc.conf": "location / {\n    root /var/www;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	locationBlocks := config.FindLocationBlocks()
+	assert.Len(t, locationBlocks, 1)
+
+	for _, locationBlock := range locationBlocks {
+		locationBlock.AddDirective(NewDirective("expires", []string{"1d"}), false, true)
+	}
+
+	assert.Len(t, config.GetConfigFile("loc.conf").FindDirectives("expires"), 1)
+}
@@ -57,5 +57,5 @@ func (l *LocationBlock) AddLocationBlock(modifier, match string, begining bool)
 }
 
 func (l *LocationBlock) DeleteLocationBlock(locationBlock LocationBlock) {
-	deleteBlock(l.rawBlock, locationBlock.Block)
+	l.deleteBlock(locationBlock.Block)
 }
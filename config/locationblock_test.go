@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +13,8 @@ func TestLocationBlock(t *testing.T) {
 	serverBlocks := config.FindServerBlocksByServerName("example.com")
 	assert.Len(t, serverBlocks, 2)
 
-	serverBlock := serverBlocks[0]
+	serverBlock := serverBlocks[1]
+	assert.Equal(t, "nginx.conf", filepath.Base(serverBlock.FilePath))
 	locationBlocks := serverBlock.FindLocationBlocks()
 	assert.Len(t, locationBlocks, 7)
 
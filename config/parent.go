@@ -0,0 +1,160 @@
+package config
+
+import "github.com/r2dtools/gonginxconf/internal/rawparser"
+
+// Container is a block or a configuration file, Parent and Ancestors return
+// *HttpBlock, *ServerBlock, *LocationBlock, *UpstreamBlock, *Block or *ConfigFile
+type Container interface {
+	FindDirectives(directiveName string) []Directive
+	FindBlocks(blockName string) []Block
+}
+
+// Parent returns the enclosing block. Directives at the top level of an included file belong
+// to the block containing the include directive, ConfigFile is returned at the top level of
+// the main configuration file. Nil is returned if the directive is not attached to a parsed file.
+func (d *Directive) Parent() Container {
+	return d.config.getParent(d.filePath, d.container, d.includedBy)
+}
+
+// Ancestors returns enclosing blocks from the nearest one up to the configuration file, see Parent
+func (d *Directive) Ancestors() []Container {
+	return getAncestors(d.Parent())
+}
+
+// Parent returns the enclosing block or configuration file, see Directive.Parent
+func (b *Block) Parent() Container {
+	return b.config.getParent(b.FilePath, b.container, b.includedBy)
+}
+
+// Ancestors returns enclosing blocks from the nearest one up to the configuration file, see Parent
+func (b *Block) Ancestors() []Container {
+	return getAncestors(b.Parent())
+}
+
+func getAncestors(parent Container) []Container {
+	var ancestors []Container
+
+	for parent != nil {
+		ancestors = append(ancestors, parent)
+		block, ok := getContainerBlock(parent)
+
+		if !ok {
+			break
+		}
+
+		parent = block.Parent()
+	}
+
+	return ancestors
+}
+
+// getParent follows include directives up while the container is the root of a file,
+// include cycles are stopped at the first file visited twice
+func (c *Config) getParent(filePath string, container entryContainer, includedBy *Directive) Container {
+	if c == nil {
+		return nil
+	}
+
+	visited := make(map[string]bool)
+
+	for {
+		filePath = c.getCurrentPath(filePath)
+		tree, ok := c.parsedFiles[filePath]
+
+		if !ok {
+			return nil
+		}
+
+		if rawBlock, ok := container.(*rawparser.BlockDirective); ok {
+			blockContainer := findBlockContainer(tree, rawBlock)
+
+			if blockContainer == nil {
+				return nil
+			}
+
+			return newTypedBlock(Block{
+				FilePath:   filePath,
+				config:     c,
+				container:  blockContainer,
+				rawBlock:   rawBlock,
+				includedBy: includedBy,
+			})
+		}
+
+		if includedBy == nil {
+			includedBy = c.findIncludeDirective(filePath)
+		}
+
+		if includedBy == nil || visited[filePath] {
+			return c.getConfigFile(filePath)
+		}
+
+		visited[filePath] = true
+		filePath = includedBy.filePath
+		container = includedBy.container
+		includedBy = includedBy.includedBy
+	}
+}
+
+// findIncludeDirective returns the first directive including the file
+func (c *Config) findIncludeDirective(filePath string) *Directive {
+	for _, edge := range c.includeEdges {
+		if c.getCurrentPath(edge.To) == filePath {
+			directive := edge.Directive
+
+			return &directive
+		}
+	}
+
+	return nil
+}
+
+func findBlockContainer(container entryContainer, rawBlock *rawparser.BlockDirective) entryContainer {
+	for _, entry := range container.GetEntries() {
+		if entry.BlockDirective == nil {
+			continue
+		}
+
+		if entry.BlockDirective == rawBlock {
+			return container
+		}
+
+		if blockContainer := findBlockContainer(entry.BlockDirective, rawBlock); blockContainer != nil {
+			return blockContainer
+		}
+	}
+
+	return nil
+}
+
+func newTypedBlock(block Block) Container {
+	switch block.GetName() {
+	case httpBlockName:
+		return &HttpBlock{Block: block}
+	case serverBlockName:
+		return &ServerBlock{Block: block}
+	case locationBlockName:
+		return &LocationBlock{Block: block}
+	case upstreamBlockName:
+		return &UpstreamBlock{Block: block}
+	}
+
+	return &block
+}
+
+func getContainerBlock(container Container) (*Block, bool) {
+	switch typedContainer := container.(type) {
+	case *HttpBlock:
+		return &typedContainer.Block, true
+	case *ServerBlock:
+		return &typedContainer.Block, true
+	case *LocationBlock:
+		return &typedContainer.Block, true
+	case *UpstreamBlock:
+		return &typedContainer.Block, true
+	case *Block:
+		return typedContainer, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParent(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":            "user www-data;\nhttp {\n    include sites-enabled/*;\n}\n",
+		"sites-enabled/a.com":   "server {\n    server_name a.com;\n    ssl_certificate a.pem;\n    location ~ \\.php$ {\n        include snippets/fastcgi.conf;\n    }\n}\n",
+		"sites-enabled/b.com":   "server {\n    server_name b.com;\n    location /b {\n        include snippets/fastcgi.conf;\n    }\n}\n",
+		"snippets/fastcgi.conf": "fastcgi_index index.php;\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	directives := config.FindDirectives("ssl_certificate")
+	assert.Len(t, directives, 1)
+
+	serverBlock, ok := directives[0].Parent().(*ServerBlock)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a.com"}, serverBlock.GetServerNames())
+
+	ancestors := directives[0].Ancestors()
+	assert.Len(t, ancestors, 3)
+	_, ok = ancestors[1].(*HttpBlock)
+	assert.True(t, ok)
+	configFile, ok := ancestors[2].(*ConfigFile)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(serverRoot, "nginx.conf"), configFile.FilePath)
+
+	// a snippet included in several blocks belongs to the block it was found through
+	serverBlocks := config.FindServerBlocksByServerName("b.com")
+	assert.Len(t, serverBlocks, 1)
+	directives = serverBlocks[0].FindDirectives("fastcgi_index")
+	assert.Len(t, directives, 1)
+
+	locationBlock, ok := directives[0].Parent().(*LocationBlock)
+	assert.True(t, ok)
+	assert.Equal(t, "/b", locationBlock.GetLocationMatch())
+
+	ancestors = locationBlock.Ancestors()
+	assert.Len(t, ancestors, 3)
+	serverBlock, ok = ancestors[0].(*ServerBlock)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"b.com"}, serverBlock.GetServerNames())
+
+	directives = config.FindDirectives("user")
+	assert.Len(t, directives, 1)
+	_, ok = directives[0].Parent().(*ConfigFile)
+	assert.True(t, ok)
+	assert.Len(t, directives[0].Ancestors(), 1)
+
+	// the file is reached through the first include if the object was not found through one
+	configFile = config.GetConfigFile("fastcgi.conf")
+	directives = configFile.FindDirectives("fastcgi_index")
+	assert.Len(t, directives, 1)
+	locationBlock, ok = directives[0].Parent().(*LocationBlock)
+	assert.True(t, ok)
+	assert.Equal(t, "\\.php$", locationBlock.GetLocationMatch())
+
+	fragment, err := ParseString("listen 80;\n")
+	assert.Nil(t, err)
+	directives = fragment.FindDirectives("listen")
+	assert.Len(t, directives, 1)
+	assert.Nil(t, directives[0].Parent())
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+)
+
+const parseErrorSnippetLines = 2
+
+// ParseError is returned when a configuration file could not be parsed
+type ParseError struct {
+	FilePath string
+	Line     int
+	Column   int
+	// Token is the offending token, empty when the error occurred at the end of the file
+	Token   string
+	Message string
+	// Snippet contains the source lines surrounding the error with a marker under the offending column
+	Snippet string
+	err     error
+}
+
+func (e *ParseError) Error() string {
+	location := e.FilePath
+
+	if e.Line != 0 {
+		location = fmt.Sprintf("%s:%d:%d", e.FilePath, e.Line, e.Column)
+	}
+
+	if location == "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", location, e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.err
+}
+
+func (e *ParseError) GetPosition() Position {
+	return Position{
+		FilePath: e.FilePath,
+		Line:     e.Line,
+		Column:   e.Column,
+	}
+}
+
+// IncludeError is returned when a file pulled in by an include directive could not be read or parsed
+type IncludeError struct {
+	// Chain lists the files from the file that started parsing down to the failed one
+	Chain      []string
+	Err        error
+	serverRoot string
+}
+
+func (e *IncludeError) Error() string {
+	chain := make([]string, 0, len(e.Chain))
+
+	for _, filePath := range e.Chain {
+		if relPath, err := filepath.Rel(e.serverRoot, filePath); err == nil && !strings.HasPrefix(relPath, "..") {
+			filePath = relPath
+		}
+
+		chain = append(chain, filePath)
+	}
+
+	return fmt.Sprintf("%s: %v", strings.Join(chain, " -> "), e.Err)
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+func newParseError(filePath, content string, err error) *ParseError {
+	parseError := &ParseError{
+		FilePath: filePath,
+		Message:  err.Error(),
+		err:      err,
+	}
+
+	var participleErr participle.Error
+
+	if !errors.As(err, &participleErr) {
+		return parseError
+	}
+
+	position := participleErr.Position()
+	parseError.Line = position.Line
+	parseError.Column = position.Column
+	parseError.Message = participleErr.Message()
+
+	var unexpectedTokenErr *participle.UnexpectedTokenError
+
+	if errors.As(err, &unexpectedTokenErr) {
+		if !unexpectedTokenErr.Unexpected.EOF() {
+			parseError.Token = unexpectedTokenErr.Unexpected.Value
+		}
+	} else if position.Offset < len(content) {
+		parseError.Token = strings.TrimSpace(strings.SplitN(content[position.Offset:], "\n", 2)[0])
+	}
+
+	parseError.Snippet = getSourceSnippet(content, position.Line, position.Column)
+
+	return parseError
+}
+
+func getSourceSnippet(content string, line, column int) string {
+	if line <= 0 {
+		return ""
+	}
+
+	lines := strings.Split(content, "\n")
+
+	if line > len(lines) {
+		return ""
+	}
+
+	first := max(line-parseErrorSnippetLines, 1)
+	last := min(line+parseErrorSnippetLines, len(lines))
+	width := len(fmt.Sprint(last))
+
+	var snippet strings.Builder
+
+	for lineNumber := first; lineNumber <= last; lineNumber++ {
+		fmt.Fprintf(&snippet, "%*d | %s\n", width, lineNumber, strings.TrimRight(lines[lineNumber-1], "\r"))
+
+		if lineNumber == line && column > 0 {
+			fmt.Fprintf(&snippet, "%*s | %s^\n", width, "", getSnippetIndent(lines[lineNumber-1], column))
+		}
+	}
+
+	return snippet.String()
+}
+
+// getSnippetIndent keeps tabs of the source line so that the marker is aligned with the offending column
+func getSnippetIndent(line string, column int) string {
+	if column-1 < len(line) {
+		line = line[:column-1]
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+
+		return ' '
+	}, line)
+}
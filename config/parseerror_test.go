@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+	serverRoot := t.TempDir()
+	configFilePath := filepath.Join(serverRoot, "nginx.conf")
+	content := "events {}\n\nhttp {\n    server_name example.com\n    listen 80;\n}\n"
+	err := os.WriteFile(configFilePath, []byte(content), 0644)
+	assert.Nil(t, err)
+
+	_, err = GetConfig(serverRoot, "", false)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, configFilePath, parseErr.FilePath)
+	assert.Equal(t, 4, parseErr.Line)
+	assert.Equal(t, 28, parseErr.Column)
+	assert.Equal(t, "\n", parseErr.Token)
+	assert.Contains(t, parseErr.Snippet, "4 |     server_name example.com\n  |                            ^\n5 |")
+	assert.Contains(t, err.Error(), configFilePath+":4:28")
+}
+
+func TestParseFileError(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":                "events {}\n",
+		"sites-enabled/broken.conf": "server {\n\tlisten 80;\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	err = config.ParseFile("sites-enabled/broken.conf")
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, filepath.Join(serverRoot, "sites-enabled/broken.conf"), parseErr.FilePath)
+	assert.Equal(t, 3, parseErr.Line)
+	assert.Empty(t, parseErr.Token)
+}
+
+func TestIncludeError(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":                  "http {\n    include sites-enabled/*.conf;\n}\n",
+		"sites-enabled/example.conf":  "server {\n    server_name example.com;\n}\n",
+		"sites-enabled/x.conf":        "server {\n    server_name x.com\n}\n",
+		"sites-enabled/zexample.conf": "server {\n    server_name zexample.com;\n}\n",
+	})
+
+	_, err := GetConfig(serverRoot, "", false)
+
+	var includeErr *IncludeError
+	assert.True(t, errors.As(err, &includeErr))
+	assert.Equal(t, []string{
+		filepath.Join(serverRoot, "nginx.conf"),
+		filepath.Join(serverRoot, "sites-enabled/x.conf"),
+	}, includeErr.Chain)
+	assert.True(t, strings.HasPrefix(err.Error(), "nginx.conf -> sites-enabled/x.conf: "))
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 2, parseErr.Line)
+
+	config, err := GetConfig(serverRoot, "", true)
+	assert.Nil(t, err)
+	assert.Len(t, config.FindServerBlocks(), 2)
+
+	warnings := config.Warnings()
+	assert.Len(t, warnings, 1)
+	assert.True(t, errors.As(warnings[0], &includeErr))
+	assert.Equal(t, filepath.Join(serverRoot, "sites-enabled/x.conf"), includeErr.Chain[1])
+}
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	serverRoot := t.TempDir()
+
+	for name, content := range files {
+		filePath := filepath.Join(serverRoot, name)
+		err := os.MkdirAll(filepath.Dir(filePath), 0755)
+		assert.Nil(t, err)
+
+		err = os.WriteFile(filePath, []byte(content), 0644)
+		assert.Nil(t, err)
+	}
+
+	return serverRoot
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Position describes where an entry is located in a configuration file
+type Position struct {
+	FilePath string
+	Line     int
+	Column   int
+}
+
+// IsValid returns false for entries that were created in memory and were never parsed
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.FilePath
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.FilePath, p.Line, p.Column)
+}
+
+func newPosition(pos lexer.Position) Position {
+	return Position{
+		FilePath: pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
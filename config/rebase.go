@@ -0,0 +1,396 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/r2dtools/gonginxconf/internal/rawdumper"
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"golang.org/x/exp/slices"
+)
+
+// RebaseConflict describes an entry that was changed both in memory and on disk
+type RebaseConflict struct {
+	FilePath string
+	// Context is the path of the block containing the entry, e.g. "http > server[example.com]"
+	Context string
+	// Ours is the entry as it is in memory, empty if the entry was deleted in memory
+	Ours string
+	// Theirs is the entry as it is on disk, empty if the entry was deleted on disk
+	Theirs string
+}
+
+func (c RebaseConflict) String() string {
+	return fmt.Sprintf("%s: %s: %q <> %q", c.FilePath, c.Context, c.Ours, c.Theirs)
+}
+
+type mergeEntry struct {
+	key   string
+	entry *rawparser.Entry
+}
+
+type entriesMerger struct {
+	filePath  string
+	dumper    *rawdumper.RawDumper
+	conflicts []RebaseConflict
+	// updates of nested blocks are applied only if the whole file is merged without conflicts
+	updates []func()
+}
+
+// Rebase re-parses modified files that were changed on disk after they had been parsed
+// and replays in-memory changes on top of the new content. Added, deleted and changed
+// directives and blocks are merged if they do not overlap with changes made on disk.
+// Files with conflicting changes are left untouched and their conflicts are returned,
+// such files can still be written with the Force dump option. Modified files deleted on disk
+// are reported as conflicts with an empty Theirs field.
+func (c *Config) Rebase() ([]RebaseConflict, error) {
+	var conflicts []RebaseConflict
+
+	for _, filePath := range c.ModifiedFiles() {
+		tree, ok := c.parsedFiles[filePath]
+		state, hasState := c.fileStates[filePath]
+
+		if !ok || !hasState {
+			continue
+		}
+
+		content, err := c.fileSystem.ReadFile(filePath)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			// the file was deleted on disk, it can still be written with the Force dump option
+			ours, err := dumpTree(tree)
+
+			if err != nil {
+				return nil, err
+			}
+
+			conflicts = append(conflicts, RebaseConflict{FilePath: filePath, Ours: ours})
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !c.isChangedOnDisk(filePath, true, content) {
+			continue
+		}
+
+		baseTree, err := c.rawParser.Parse(filePath, string(state.content))
+
+		if err != nil {
+			return nil, newParseError(filePath, string(state.content), err)
+		}
+
+		theirTree, err := c.rawParser.Parse(filePath, string(content))
+
+		if err != nil {
+			return nil, newParseError(filePath, string(content), err)
+		}
+
+		merger := entriesMerger{
+			filePath: filePath,
+			dumper:   &rawdumper.RawDumper{},
+		}
+		entries := merger.merge(nil, baseTree.GetEntries(), tree.GetEntries(), theirTree.GetEntries())
+
+		if len(merger.conflicts) != 0 {
+			conflicts = append(conflicts, merger.conflicts...)
+
+			continue
+		}
+
+		for _, update := range merger.updates {
+			update()
+		}
+
+		tree.SetEntries(entries)
+		c.recordFileState(filePath, content)
+		// the merged tree can not be restored from snapshots taken before it
+		c.dropEdits(filePath)
+
+		if merged, err := dumpTree(tree); err == nil && merged == string(content) {
+			delete(c.modifiedFiles, filePath)
+		}
+	}
+
+	return conflicts, nil
+}
+
+func (m *entriesMerger) merge(context []string, base, ours, theirs []*rawparser.Entry) []*rawparser.Entry {
+	repeatable := getRepeatableIdentifiers(base, ours, theirs)
+	baseEntries := getMergeEntries(base, repeatable)
+	ourEntries := getMergeEntries(ours, repeatable)
+	theirEntries := getMergeEntries(theirs, repeatable)
+	alignServerKeys(baseEntries, ourEntries)
+	alignServerKeys(baseEntries, theirEntries)
+
+	baseMap := getMergeEntriesMap(baseEntries)
+	ourMap := getMergeEntriesMap(ourEntries)
+	theirMap := getMergeEntriesMap(theirEntries)
+
+	var result []mergeEntry
+
+	for _, theirEntry := range theirEntries {
+		baseEntry, inBase := baseMap[theirEntry.key]
+		ourEntry, inOurs := ourMap[theirEntry.key]
+
+		switch {
+		case !inBase && !inOurs:
+			result = append(result, theirEntry)
+		case !inBase:
+			// added on both sides
+			if m.isEqual(ourEntry.entry, theirEntry.entry) {
+				result = append(result, ourEntry)
+			} else {
+				m.addConflict(context, ourEntry.entry, theirEntry.entry)
+			}
+		case !inOurs:
+			// deleted in memory
+			if !m.isEqual(baseEntry.entry, theirEntry.entry) {
+				m.addConflict(context, nil, theirEntry.entry)
+			}
+		default:
+			if entry := m.mergeEntry(context, baseEntry.entry, ourEntry.entry, theirEntry.entry); entry != nil {
+				result = append(result, mergeEntry{key: theirEntry.key, entry: entry})
+			}
+		}
+	}
+
+	// deleted on disk
+	for _, baseEntry := range baseEntries {
+		ourEntry, inOurs := ourMap[baseEntry.key]
+
+		if _, inTheirs := theirMap[baseEntry.key]; !inTheirs && inOurs && !m.isEqual(baseEntry.entry, ourEntry.entry) {
+			m.addConflict(context, ourEntry.entry, nil)
+		}
+	}
+
+	// added in memory, inserted after the entry they follow in memory
+	for index, ourEntry := range ourEntries {
+		if _, inBase := baseMap[ourEntry.key]; inBase {
+			continue
+		}
+
+		if _, inTheirs := theirMap[ourEntry.key]; inTheirs {
+			continue
+		}
+
+		position := 0
+
+		for prevIndex := index - 1; prevIndex >= 0; prevIndex-- {
+			prevKey := ourEntries[prevIndex].key
+			resultIndex := slices.IndexFunc(result, func(entry mergeEntry) bool {
+				return entry.key == prevKey
+			})
+
+			if resultIndex != -1 {
+				position = resultIndex + 1
+				break
+			}
+		}
+
+		result = slices.Insert(result, position, ourEntry)
+	}
+
+	entries := make([]*rawparser.Entry, 0, len(result))
+
+	for _, entry := range result {
+		entries = append(entries, entry.entry)
+	}
+
+	return entries
+}
+
+// mergeEntry returns the merged entry or nil if it has to be removed
+func (m *entriesMerger) mergeEntry(context []string, base, ours, theirs *rawparser.Entry) *rawparser.Entry {
+	if m.isEqual(ours, base) || m.isEqual(ours, theirs) {
+		if m.isEqual(ours, theirs) {
+			return ours
+		}
+
+		return theirs
+	}
+
+	if m.isEqual(theirs, base) {
+		return ours
+	}
+
+	if ours.BlockDirective != nil && theirs.BlockDirective != nil && base.BlockDirective != nil {
+		blockContext := append(slices.Clone(context), getBlockContextName(ours.BlockDirective))
+		entries := m.merge(
+			blockContext,
+			base.BlockDirective.GetEntries(),
+			ours.BlockDirective.GetEntries(),
+			theirs.BlockDirective.GetEntries(),
+		)
+		block := ours.BlockDirective
+		m.updates = append(m.updates, func() {
+			setEntries(block, entries)
+		})
+
+		return ours
+	}
+
+	m.addConflict(context, ours, theirs)
+
+	return ours
+}
+
+func getMergeEntries(entries []*rawparser.Entry, repeatable map[string]bool) []mergeEntry {
+	var mergeEntries []mergeEntry
+	occurrences := make(map[string]int)
+
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+
+		key := getEntryKey(entry, repeatable)
+		occurrences[key]++
+
+		if occurrences[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, occurrences[key])
+		}
+
+		mergeEntries = append(mergeEntries, mergeEntry{key: key, entry: entry})
+	}
+
+	return mergeEntries
+}
+
+// getEntryKey identifies an entry in all three versions: blocks by their name and parameters,
+// server blocks by their server names, directives by their name and also by values if they can be repeated
+func getEntryKey(entry *rawparser.Entry, repeatable map[string]bool) string {
+	switch {
+	case entry.Comment != nil:
+		return "#" + strings.TrimSpace(entry.Comment.Value)
+	case entry.BlockDirective != nil:
+		// blocks with the same context name are matched by their order
+		return "{" + getBlockContextName(entry.BlockDirective)
+	case entry.Directive != nil:
+		identifier := entry.Directive.Identifier
+
+		if repeatable[identifier] {
+			return identifier + " " + strings.Join(entry.Directive.GetExpressions(), " ")
+		}
+
+		return identifier
+	}
+
+	return ""
+}
+
+func (m *entriesMerger) isEqual(first, second *rawparser.Entry) bool {
+	return m.dumper.DumpEntry(first) == m.dumper.DumpEntry(second)
+}
+
+func (m *entriesMerger) addConflict(context []string, ours, theirs *rawparser.Entry) {
+	conflict := RebaseConflict{
+		FilePath: m.filePath,
+		Context:  strings.Join(context, " > "),
+	}
+
+	if ours != nil {
+		conflict.Ours = m.dumper.DumpEntry(ours)
+	}
+
+	if theirs != nil {
+		conflict.Theirs = m.dumper.DumpEntry(theirs)
+	}
+
+	m.conflicts = append(m.conflicts, conflict)
+}
+
+func getRepeatableIdentifiers(entryLists ...[]*rawparser.Entry) map[string]bool {
+	repeatable := make(map[string]bool)
+
+	for _, identifier := range repeatableDirectives {
+		repeatable[identifier] = true
+	}
+
+	for _, entries := range entryLists {
+		counts := make(map[string]int)
+
+		for _, entry := range entries {
+			if entry == nil || entry.Directive == nil {
+				continue
+			}
+
+			counts[entry.Directive.Identifier]++
+
+			if counts[entry.Directive.Identifier] > 1 {
+				repeatable[entry.Directive.Identifier] = true
+			}
+		}
+	}
+
+	return repeatable
+}
+
+// alignServerKeys gives a server block the key of the base server block sharing a server name with it,
+// so that server blocks are still matched after a server name was added or removed on one side
+func alignServerKeys(baseEntries, entries []mergeEntry) {
+	baseMap := getMergeEntriesMap(baseEntries)
+	usedKeys := make(map[string]bool)
+
+	for _, entry := range entries {
+		usedKeys[entry.key] = true
+	}
+
+	for index, entry := range entries {
+		if _, inBase := baseMap[entry.key]; inBase || !isServerBlockEntry(entry.entry) {
+			continue
+		}
+
+		serverNames := getServerNames(entry.entry.BlockDirective)
+
+		for _, baseEntry := range baseEntries {
+			if usedKeys[baseEntry.key] || !isServerBlockEntry(baseEntry.entry) {
+				continue
+			}
+
+			if slices.ContainsFunc(getServerNames(baseEntry.entry.BlockDirective), func(serverName string) bool {
+				return slices.Contains(serverNames, serverName)
+			}) {
+				usedKeys[baseEntry.key] = true
+				entries[index].key = baseEntry.key
+
+				break
+			}
+		}
+	}
+}
+
+func isServerBlockEntry(entry *rawparser.Entry) bool {
+	return entry.BlockDirective != nil && entry.BlockDirective.Identifier == serverBlockName
+}
+
+func getMergeEntriesMap(entries []mergeEntry) map[string]mergeEntry {
+	entriesMap := make(map[string]mergeEntry, len(entries))
+
+	for _, entry := range entries {
+		entriesMap[entry.key] = entry
+	}
+
+	return entriesMap
+}
+
+// getBlockContextName returns a short block description, e.g. "server[example.com]" or "location[~ \.php$]".
+// Server blocks are described by their server names.
+func getBlockContextName(block *rawparser.BlockDirective) string {
+	parameters := block.GetParametersExpressions()
+
+	if block.Identifier == serverBlockName && len(parameters) == 0 {
+		parameters = getServerNames(block)
+	}
+
+	if len(parameters) == 0 {
+		return block.Identifier
+	}
+
+	return fmt.Sprintf("%s[%s]", block.Identifier, strings.Join(parameters, " "))
+}
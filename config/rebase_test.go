@@ -0,0 +1,143 @@
+package config
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebase(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+	exampleFileName := "etc/nginx/sites-enabled/example.com.conf"
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	serverBlocks := config.FindServerBlocksByServerName("example.com")
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].AddDirective(NewDirective("listen", []string{"443", "ssl"}), false, false)
+
+	externalContent := "server {\n    server_name example.com;\n    root /var/www/example.com;\n    location ~ \\.php$ {\n        include snippets/fastcgi.conf;\n    }\n}\n"
+	fsys.MapFS[exampleFileName] = &fstest.MapFile{Data: []byte(externalContent)}
+
+	conflicts, err := config.Rebase()
+	assert.Nil(t, err)
+	assert.Empty(t, conflicts)
+
+	err = config.Dump()
+	assert.Nil(t, err)
+
+	content := string(fsys.MapFS[exampleFileName].Data)
+	assert.Contains(t, content, "root /var/www/example.com;")
+	assert.Contains(t, content, "listen 443 ssl;")
+	assert.Contains(t, content, "include snippets/fastcgi.conf;")
+}
+
+func TestRebaseConflict(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+	exampleFileName := "etc/nginx/sites-enabled/example.com.conf"
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	locationBlocks := config.FindLocationBlocks()
+	assert.Len(t, locationBlocks, 1)
+	locationBlocks[0].AddDirective(NewDirective("fastcgi_pass", []string{"unix:/run/php/php-fpm.sock"}), false, false)
+
+	externalContent := "server {\n    server_name example.com;\n    location ~ \\.php$ {\n        fastcgi_pass 127.0.0.1:9000;\n        include snippets/fastcgi.conf;\n    }\n}\n"
+	fsys.MapFS[exampleFileName] = &fstest.MapFile{Data: []byte(externalContent)}
+
+	conflicts, err := config.Rebase()
+	assert.Nil(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "/"+exampleFileName, conflicts[0].FilePath)
+	assert.Equal(t, "server[example.com] > location[~ \\.php$]", conflicts[0].Context)
+	assert.Equal(t, "fastcgi_pass unix:/run/php/php-fpm.sock;", conflicts[0].Ours)
+	assert.Equal(t, "fastcgi_pass 127.0.0.1:9000;", conflicts[0].Theirs)
+
+	// the file is left untouched
+	assert.True(t, config.GetConfigFile("example.com.conf").IsModified())
+	assert.Len(t, config.FindDirectives("fastcgi_pass"), 1)
+	assert.Equal(t, []string{"unix:/run/php/php-fpm.sock"}, config.FindDirectives("fastcgi_pass")[0].GetValues())
+}
+
+func TestRebaseDeleteConflict(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+	exampleFileName := "etc/nginx/sites-enabled/example.com.conf"
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	serverBlocks := config.FindServerBlocks()
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].DeleteLocationBlock(config.FindLocationBlocks()[0])
+
+	externalContent := "server {\n    server_name example.com;\n    location ~ \\.php$ {\n        include snippets/fastcgi.conf;\n        fastcgi_index index.php;\n    }\n}\n"
+	fsys.MapFS[exampleFileName] = &fstest.MapFile{Data: []byte(externalContent)}
+
+	conflicts, err := config.Rebase()
+	assert.Nil(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "server[example.com]", conflicts[0].Context)
+	assert.Empty(t, conflicts[0].Ours)
+	assert.Contains(t, conflicts[0].Theirs, "fastcgi_index index.php;")
+}
+
+func TestRebaseServerBlocks(t *testing.T) {
+	fsys := writableMapFS{MapFS: fstest.MapFS{
+		"etc/nginx/nginx.conf": &fstest.MapFile{
+			Data: []byte("http {\n    server {\n        server_name a.com;\n    }\n    server {\n        server_name b.com;\n    }\n}\n"),
+		},
+	}}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	config.FindServerBlocksByServerName("a.com")[0].AddDirective(NewDirective("return", []string{"444"}), false, true)
+
+	// the server block is deleted on disk
+	fsys.MapFS["etc/nginx/nginx.conf"] = &fstest.MapFile{Data: []byte("http {\n    server {\n        server_name b.com;\n    }\n}\n")}
+
+	conflicts, err := config.Rebase()
+	assert.Nil(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "http", conflicts[0].Context)
+	assert.Contains(t, conflicts[0].Ours, "return 444;")
+	assert.Empty(t, conflicts[0].Theirs)
+	assert.Len(t, config.FindServerBlocks(), 2)
+
+	// server blocks are reordered on disk
+	fsys.MapFS["etc/nginx/nginx.conf"] = &fstest.MapFile{
+		Data: []byte("http {\n    server {\n        server_name b.com;\n        listen 8080;\n    }\n    server {\n        server_name a.com;\n    }\n}\n"),
+	}
+
+	conflicts, err = config.Rebase()
+	assert.Nil(t, err)
+	assert.Empty(t, conflicts)
+
+	serverBlocks := config.FindServerBlocks()
+	assert.Len(t, serverBlocks, 2)
+	assert.Equal(t, []string{"b.com"}, serverBlocks[0].GetServerNames())
+	assert.Len(t, serverBlocks[0].FindDirectives("listen"), 1)
+	assert.Empty(t, serverBlocks[0].FindDirectives("return"))
+	assert.Equal(t, []string{"a.com"}, serverBlocks[1].GetServerNames())
+	assert.Len(t, serverBlocks[1].FindDirectives("return"), 1)
+}
+
+func TestRebaseDeletedFile(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+	exampleFileName := "etc/nginx/sites-enabled/example.com.conf"
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+	config.FindServerBlocks()[0].AddDirective(NewDirective("listen", []string{"443", "ssl"}), false, true)
+
+	delete(fsys.MapFS, exampleFileName)
+
+	conflicts, err := config.Rebase()
+	assert.Nil(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "/"+exampleFileName, conflicts[0].FilePath)
+	assert.Contains(t, conflicts[0].Ours, "listen 443 ssl;")
+	assert.Empty(t, conflicts[0].Theirs)
+}
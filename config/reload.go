@@ -0,0 +1,182 @@
+package config
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sort"
+
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+)
+
+var ErrPendingFileChanges = errors.New("files were added, removed, renamed or linked in memory and not dumped yet")
+
+// ReloadResult lists files affected by Reload, paths are sorted
+type ReloadResult struct {
+	// Added are files that were not a part of the configuration before, e.g. newly matched by include masks
+	Added []string
+	// Changed are files that were changed on disk and parsed again
+	Changed []string
+	// Removed are files that vanished or are not included anymore
+	Removed []string
+	// Conflicts are files that were changed both on disk and in memory,
+	// in-memory changes are kept and can be merged with Rebase
+	Conflicts []string
+}
+
+// IsEmpty reports that nothing was changed on disk
+func (r *ReloadResult) IsEmpty() bool {
+	return len(r.Added) == 0 && len(r.Changed) == 0 && len(r.Removed) == 0 && len(r.Conflicts) == 0
+}
+
+type reloadState struct {
+	files     map[string]*rawparser.Config
+	states    map[string]fileState
+	modified  map[string]bool
+	changed   []string
+	conflicts []string
+}
+
+// Reload updates the configuration from disk. Only files which size, modification time or content
+// changed are parsed again, trees of other files are kept, so objects found before stay valid.
+// Include masks are matched again, files that are not included anymore are dropped.
+// The configuration is left unchanged if Reload fails.
+func (c *Config) Reload() (*ReloadResult, error) {
+	if len(c.newFiles) != 0 || len(c.removedFiles) != 0 || len(c.renamedFiles) != 0 ||
+		len(c.newSymlinks) != 0 || len(c.removedSymlinks) != 0 {
+		return nil, ErrPendingFileChanges
+	}
+
+	previousFiles := c.parsedFiles
+	previousStates := c.fileStates
+	previousModified := c.modifiedFiles
+	previousEdges := c.includeEdges
+	previousWarnings := c.warnings
+
+	c.reload = &reloadState{
+		files:    c.parsedFiles,
+		states:   c.fileStates,
+		modified: c.modifiedFiles,
+	}
+
+	defer func() {
+		c.reload = nil
+	}()
+
+	c.parsedFiles = make(map[string]*rawparser.Config)
+	c.fileStates = make(map[string]fileState)
+	c.modifiedFiles = make(map[string]bool)
+	c.includeEdges = nil
+	c.warnings = nil
+
+	for _, filePath := range append([]string{c.configRoot}, c.extraFiles...) {
+		if _, err := c.parseRecursively(filePath, nil); err != nil {
+			c.parsedFiles = previousFiles
+			c.fileStates = previousStates
+			c.modifiedFiles = previousModified
+			c.includeEdges = previousEdges
+			c.warnings = previousWarnings
+
+			return nil, err
+		}
+	}
+
+	result := &ReloadResult{
+		Changed:   c.reload.changed,
+		Conflicts: c.reload.conflicts,
+	}
+
+	for filePath := range c.parsedFiles {
+		if _, ok := previousFiles[filePath]; !ok {
+			result.Added = append(result.Added, filePath)
+		}
+	}
+
+	for filePath := range previousFiles {
+		if _, ok := c.parsedFiles[filePath]; !ok {
+			result.Removed = append(result.Removed, filePath)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Changed)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Conflicts)
+
+	return result, nil
+}
+
+// loadFile parses the file unless Reload can keep its previous tree
+func (c *Config) loadFile(filePath string) (*rawparser.Config, error) {
+	if c.reload == nil {
+		return c.parseFile(filePath)
+	}
+
+	tree, ok := c.reload.files[filePath]
+
+	if !ok {
+		return c.parseFile(filePath)
+	}
+
+	state := c.reload.states[filePath]
+	unchanged, err := c.isUnchangedOnDisk(filePath, &state)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c.reload.modified[filePath] {
+		if !unchanged {
+			c.reload.conflicts = append(c.reload.conflicts, filePath)
+		}
+
+		// the base of in-memory changes is kept for Dump and Rebase
+		c.fileStates[filePath] = c.reload.states[filePath]
+		c.modifiedFiles[filePath] = true
+
+		return tree, nil
+	}
+
+	if unchanged {
+		c.fileStates[filePath] = state
+
+		return tree, nil
+	}
+
+	tree, err = c.parseFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.reload.changed = append(c.reload.changed, filePath)
+
+	return tree, nil
+}
+
+// isUnchangedOnDisk compares size and modification time of the file first and its content
+// only if they differ. The modification time of the state is updated if only it was changed.
+func (c *Config) isUnchangedOnDisk(filePath string, state *fileState) (bool, error) {
+	stat, err := c.fileSystem.Stat(filePath)
+
+	if err != nil {
+		return false, err
+	}
+
+	if stat.Size() == state.size && !state.modTime.IsZero() && stat.ModTime().Equal(state.modTime) {
+		return true, nil
+	}
+
+	content, err := c.fileSystem.ReadFile(filePath)
+
+	if err != nil {
+		return false, err
+	}
+
+	if sha256.Sum256(content) != state.hash {
+		return false, nil
+	}
+
+	state.modTime = stat.ModTime()
+
+	return true, nil
+}
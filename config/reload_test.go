@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":    "http {\n    include conf.d/*.conf;\n}\n",
+		"conf.d/a.conf": "server {\n    server_name a.com;\n}\n",
+		"conf.d/b.conf": "server {\n    server_name b.com;\n}\n",
+	})
+	aConfigPath := filepath.Join(serverRoot, "conf.d/a.conf")
+	bConfigPath := filepath.Join(serverRoot, "conf.d/b.conf")
+	cConfigPath := filepath.Join(serverRoot, "conf.d/c.conf")
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+	aTree := config.parsedFiles[aConfigPath]
+
+	result, err := config.Reload()
+	assert.Nil(t, err)
+	assert.True(t, result.IsEmpty())
+
+	err = os.WriteFile(bConfigPath, []byte("server {\n    server_name b.com www.b.com;\n}\n"), 0644)
+	assert.Nil(t, err)
+	err = os.WriteFile(cConfigPath, []byte("server {\n    server_name c.com;\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	result, err = config.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{cConfigPath}, result.Added)
+	assert.Equal(t, []string{bConfigPath}, result.Changed)
+	assert.Empty(t, result.Removed)
+	assert.Same(t, aTree, config.parsedFiles[aConfigPath])
+	assert.Len(t, config.FindServerBlocksByServerName("www.b.com"), 1)
+	assert.Len(t, config.FindServerBlocksByServerName("c.com"), 1)
+
+	err = os.Remove(cConfigPath)
+	assert.Nil(t, err)
+
+	result, err = config.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{cConfigPath}, result.Removed)
+	assert.Empty(t, config.FindServerBlocksByServerName("c.com"))
+}
+
+func TestReloadKeepsInMemoryChanges(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":    "http {\n    include conf.d/*.conf;\n}\n",
+		"conf.d/a.conf": "server {\n    server_name a.com;\n}\n",
+	})
+	aConfigPath := filepath.Join(serverRoot, "conf.d/a.conf")
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	serverBlocks := config.FindServerBlocksByServerName("a.com")
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].AddDirective(NewDirective("listen", []string{"80"}), false, false)
+
+	err = os.WriteFile(aConfigPath, []byte("server {\n    server_name a.com www.a.com;\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	result, err := config.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{aConfigPath}, result.Conflicts)
+	assert.Len(t, config.FindDirectives("listen"), 1)
+	assert.Equal(t, []string{aConfigPath}, config.ModifiedFiles())
+
+	conflicts, err := config.Rebase()
+	assert.Nil(t, err)
+	assert.Empty(t, conflicts)
+	assert.Len(t, config.FindServerBlocksByServerName("www.a.com"), 1)
+	assert.Len(t, config.FindDirectives("listen"), 1)
+}
+
+func TestReloadFailure(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":    "http {\n    include conf.d/*.conf;\n}\n",
+		"conf.d/a.conf": "server {\n    server_name a.com;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	err = os.WriteFile(filepath.Join(serverRoot, "conf.d/a.conf"), []byte("server {\n    server_name a.com\n"), 0644)
+	assert.Nil(t, err)
+
+	_, err = config.Reload()
+	assert.NotNil(t, err)
+	assert.Len(t, config.FindServerBlocksByServerName("a.com"), 1)
+
+	_, err = config.AddConfigFile(filepath.Join(serverRoot, "conf.d/b.conf"))
+	assert.Nil(t, err)
+
+	_, err = config.Reload()
+	assert.ErrorIs(t, err, ErrPendingFileChanges)
+}
@@ -0,0 +1,549 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+)
+
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Node is a directive or a block returned by Select: *Directive, *HttpBlock, *ServerBlock,
+// *LocationBlock, *UpstreamBlock or *Block
+type Node interface {
+	GetName() string
+	GetContextPath() string
+	Parent() Container
+}
+
+const (
+	matchExact  = "="
+	matchPrefix = "^="
+	matchRegexp = "~="
+)
+
+type selector struct {
+	steps []selectorStep
+}
+
+type selectorStep struct {
+	// child is set if the step is separated from the previous one with ">"
+	child   bool
+	name    string
+	filters []selectorFilter
+	has     []selector
+}
+
+// selectorFilter matches values of child directives with the name or parameters of the node itself if the name is empty.
+// Values match if all of them joined with spaces or any single value match.
+type selectorFilter struct {
+	name     string
+	operator string
+	value    string
+	regexp   *regexp.Regexp
+	// modifier is set for filters of location modifiers, e.g. [~ ^/api], the value matches the path then
+	modifier string
+}
+
+type selectorNode struct {
+	filePath   string
+	container  entryContainer
+	entry      *rawparser.Entry
+	includedBy *Directive
+	guard      includeGuard
+}
+
+// selectorNodeKey tells nodes apart by their entry and includes they were found through,
+// so that a node of a file included several times is matched at every place
+type selectorNodeKey struct {
+	entry    *rawparser.Entry
+	includes string
+}
+
+var selectorFilterRegexp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*(\^=|~=|=)\s*(.*)$`)
+
+var selectorModifierRegexp = regexp.MustCompile(`^(~\*|~|\^~|=)\s+(.*)$`)
+
+// Select returns directives and blocks matching the selector in nginx load order, e.g.
+// "http > server[server_name=example.com] > location[~ ^/api] > proxy_pass".
+// Steps separated with ">" match children, steps separated with spaces match descendants, "*" matches any name.
+// A selector starting with ">" matches top level nodes only. Nodes of a file included several times
+// are returned at every place they are included.
+// Filters in square brackets match:
+//   - [server_name=example.com] a child directive with a value equal to the given one,
+//     "^=" matches a prefix and "~=" a regular expression
+//   - [^=/api] and [~=^/api] parameters of the block or values of the directive
+//   - [~ ^/api] locations with the modifier ("~", "~*", "^~" or "=") which path starts with the given one,
+//     e.g. "location ~ ^/api/v1", [~ ~=v[0-9]] matches the path with a regular expression
+//   - [/api ssl] exactly the given parameters
+//
+// :has(selector) matches blocks containing nodes matching the selector, e.g. "server:has(> listen[443 ssl])".
+func (c *Config) Select(selector string) ([]Node, error) {
+	var roots []selectorNode
+
+	for _, filePath := range c.getRootFiles() {
+		roots = append(roots, c.getSelectorChildren(filePath, c.parsedFiles[filePath], nil, includeGuard{stack: []string{filePath}})...)
+	}
+
+	return c.selectNodes(selector, roots)
+}
+
+// Select returns nodes of the file matching the selector, see Config.Select
+func (c *ConfigFile) Select(selector string) ([]Node, error) {
+	return c.config.selectNodes(selector, c.config.getSelectorChildren(c.FilePath, c.configFile, nil, includeGuard{}))
+}
+
+// Select returns nodes inside the block matching the selector, see Config.Select
+func (b *Block) Select(selector string) ([]Node, error) {
+	return b.config.selectNodes(selector, b.config.getSelectorChildren(b.FilePath, b.rawBlock, b.includedBy, includeGuard{}))
+}
+
+func (c *Config) selectNodes(query string, roots []selectorNode) ([]Node, error) {
+	selector, err := parseSelector(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []Node
+
+	for _, node := range c.matchSelector(selector, roots) {
+		nodes = append(nodes, c.newNode(node))
+	}
+
+	return nodes, nil
+}
+
+// matchSelector applies steps one by one, roots are children of the scope of the selector
+func (c *Config) matchSelector(selector selector, roots []selectorNode) []selectorNode {
+	var matched []selectorNode
+
+	for index, step := range selector.steps {
+		var candidates []selectorNode
+
+		if index == 0 {
+			candidates = roots
+
+			if !step.child {
+				candidates = c.getSelectorDescendants(roots)
+			}
+		} else {
+			for _, node := range matched {
+				children := c.getSelectorChildren(node.filePath, node.entry.BlockDirective, node.includedBy, node.guard)
+
+				if step.child {
+					candidates = append(candidates, children...)
+				} else {
+					candidates = append(candidates, c.getSelectorDescendants(children)...)
+				}
+			}
+		}
+
+		matched = nil
+		seen := make(map[selectorNodeKey]bool)
+
+		for _, candidate := range candidates {
+			key := candidate.getKey()
+
+			if !seen[key] && c.matchStep(step, candidate) {
+				seen[key] = true
+				matched = append(matched, candidate)
+			}
+		}
+	}
+
+	return matched
+}
+
+func (n selectorNode) getKey() selectorNodeKey {
+	var includes []string
+
+	for include := n.includedBy; include != nil; include = include.includedBy {
+		includes = append(includes, fmt.Sprintf("%p", include.rawDirective))
+	}
+
+	return selectorNodeKey{entry: n.entry, includes: strings.Join(includes, " ")}
+}
+
+func (c *Config) matchStep(step selectorStep, node selectorNode) bool {
+	var name string
+	var values []string
+
+	if node.entry.Directive != nil {
+		name = node.entry.Directive.Identifier
+		values = unquoteValues(node.entry.Directive.GetExpressions())
+	} else {
+		name = node.entry.BlockDirective.Identifier
+		values = unquoteValues(node.entry.BlockDirective.GetParametersExpressions())
+	}
+
+	if step.name != "*" && step.name != name {
+		return false
+	}
+
+	for _, filter := range step.filters {
+		if filter.name == "" {
+			if !filter.matchValues(values) {
+				return false
+			}
+
+			continue
+		}
+
+		if node.entry.BlockDirective == nil || !c.matchChildDirective(filter, node) {
+			return false
+		}
+	}
+
+	for _, has := range step.has {
+		if node.entry.BlockDirective == nil {
+			return false
+		}
+
+		children := c.getSelectorChildren(node.filePath, node.entry.BlockDirective, node.includedBy, node.guard)
+
+		if len(c.matchSelector(has, children)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Config) matchChildDirective(filter selectorFilter, node selectorNode) bool {
+	for _, child := range c.getSelectorChildren(node.filePath, node.entry.BlockDirective, node.includedBy, node.guard) {
+		directive := child.entry.Directive
+
+		if directive == nil || directive.Identifier != filter.name {
+			continue
+		}
+
+		if filter.matchValues(unquoteValues(directive.GetExpressions())) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unquoteValues allows to match quoted values as nginx treats them, e.g. "example.com" as example.com
+func unquoteValues(values []string) []string {
+	unquoted := make([]string, 0, len(values))
+
+	for _, value := range values {
+		unquoted = append(unquoted, strings.Trim(value, " \"'"))
+	}
+
+	return unquoted
+}
+
+// matchValues matches all values joined with spaces or any of them
+func (f selectorFilter) matchValues(values []string) bool {
+	if f.modifier != "" {
+		return len(values) == 2 && values[0] == f.modifier && f.match(values[1])
+	}
+
+	if f.match(strings.Join(values, " ")) {
+		return true
+	}
+
+	for _, value := range values {
+		if f.match(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f selectorFilter) match(value string) bool {
+	switch f.operator {
+	case matchPrefix:
+		return strings.HasPrefix(value, f.value)
+	case matchRegexp:
+		return f.regexp.MatchString(value)
+	}
+
+	return value == f.value
+}
+
+// getSelectorChildren returns entries of the container, entries of included files replace include directives
+// which are returned too
+func (c *Config) getSelectorChildren(filePath string, container entryContainer, includedBy *Directive, guard includeGuard) []selectorNode {
+	var nodes []selectorNode
+
+	if c == nil || container == nil {
+		return nodes
+	}
+
+	for _, entry := range container.GetEntries() {
+		if entry.Directive == nil && entry.BlockDirective == nil {
+			continue
+		}
+
+		nodes = append(nodes, selectorNode{
+			filePath:   filePath,
+			container:  container,
+			entry:      entry,
+			includedBy: includedBy,
+			guard:      guard,
+		})
+
+		if entry.Directive == nil || entry.Directive.Identifier != "include" {
+			continue
+		}
+
+		includeFiles, err := c.findIncludedFiles(entry.Directive.GetFirstValueStr())
+
+		if err != nil {
+			continue
+		}
+
+		include := &Directive{
+			rawDirective: entry.Directive,
+			container:    container,
+			config:       c,
+			filePath:     filePath,
+			includedBy:   includedBy,
+		}
+
+		for _, includePath := range includeFiles {
+			includeConfig, ok := c.parsedFiles[includePath]
+
+			if !ok {
+				continue
+			}
+
+			includeGuard, ok := guard.enter(includePath)
+
+			if !ok {
+				continue
+			}
+
+			nodes = append(nodes, c.getSelectorChildren(includePath, includeConfig, include, includeGuard)...)
+		}
+	}
+
+	return nodes
+}
+
+func (c *Config) getSelectorDescendants(nodes []selectorNode) []selectorNode {
+	var descendants []selectorNode
+
+	for _, node := range nodes {
+		descendants = append(descendants, node)
+
+		if node.entry.BlockDirective != nil {
+			children := c.getSelectorChildren(node.filePath, node.entry.BlockDirective, node.includedBy, node.guard)
+			descendants = append(descendants, c.getSelectorDescendants(children)...)
+		}
+	}
+
+	return descendants
+}
+
+func (c *Config) newNode(node selectorNode) Node {
+	if node.entry.Directive != nil {
+		return &Directive{
+			rawDirective: node.entry.Directive,
+			container:    node.container,
+			config:       c,
+			filePath:     node.filePath,
+			includedBy:   node.includedBy,
+		}
+	}
+
+	return newTypedBlock(Block{
+		FilePath:   node.filePath,
+		config:     c,
+		container:  node.container,
+		rawBlock:   node.entry.BlockDirective,
+		includedBy: node.includedBy,
+	}).(Node)
+}
+
+// parseSelector parses steps of the selector, a selector starting with ">" matches children of the scope only
+func parseSelector(query string) (selector, error) {
+	parser := selectorParser{query: query}
+	result, err := parser.parse()
+
+	if err != nil {
+		return result, fmt.Errorf("%w %q: %s at position %d", ErrInvalidSelector, query, err, parser.offset)
+	}
+
+	return result, nil
+}
+
+type selectorParser struct {
+	query  string
+	offset int
+}
+
+func (p *selectorParser) parse() (selector, error) {
+	var result selector
+
+	p.skipSpaces()
+
+	for p.offset < len(p.query) {
+		step := selectorStep{}
+
+		if p.query[p.offset] == '>' {
+			step.child = true
+			p.offset++
+			p.skipSpaces()
+		}
+
+		start := p.offset
+
+		for p.offset < len(p.query) && !strings.ContainsRune(" \t\n>[:", rune(p.query[p.offset])) {
+			p.offset++
+		}
+
+		step.name = p.query[start:p.offset]
+
+		if step.name == "" {
+			return result, errors.New("name expected")
+		}
+
+		for p.offset < len(p.query) {
+			if p.query[p.offset] == '[' {
+				filter, err := p.parseFilter()
+
+				if err != nil {
+					return result, err
+				}
+
+				step.filters = append(step.filters, filter)
+			} else if strings.HasPrefix(p.query[p.offset:], ":has(") {
+				has, err := p.parseHas()
+
+				if err != nil {
+					return result, err
+				}
+
+				step.has = append(step.has, has)
+			} else if p.query[p.offset] == ':' {
+				return result, errors.New("unknown pseudo-class")
+			} else {
+				break
+			}
+		}
+
+		result.steps = append(result.steps, step)
+		p.skipSpaces()
+	}
+
+	if len(result.steps) == 0 {
+		return result, errors.New("selector is empty")
+	}
+
+	return result, nil
+}
+
+func (p *selectorParser) parseFilter() (selectorFilter, error) {
+	var filter selectorFilter
+	var content strings.Builder
+
+	p.offset++
+	quoted := false
+
+	for ; p.offset < len(p.query); p.offset++ {
+		char := p.query[p.offset]
+
+		if char == '"' {
+			quoted = !quoted
+
+			continue
+		}
+
+		if char == ']' && !quoted {
+			break
+		}
+
+		content.WriteByte(char)
+	}
+
+	if p.offset == len(p.query) {
+		return filter, errors.New("\"]\" expected")
+	}
+
+	p.offset++
+	value := strings.TrimSpace(content.String())
+
+	if matches := selectorModifierRegexp.FindStringSubmatch(value); matches != nil {
+		filter.modifier, value = matches[1], strings.TrimSpace(matches[2])
+	}
+
+	if matches := selectorFilterRegexp.FindStringSubmatch(value); matches != nil && filter.modifier == "" {
+		filter.name, filter.operator, filter.value = matches[1], matches[2], matches[3]
+	} else if strings.HasPrefix(value, matchPrefix) || strings.HasPrefix(value, matchRegexp) {
+		filter.operator, filter.value = value[:2], strings.TrimSpace(value[2:])
+	} else if filter.modifier != "" {
+		filter.operator, filter.value = matchPrefix, value
+	} else {
+		filter.operator, filter.value = matchExact, value
+	}
+
+	if filter.operator == matchRegexp {
+		var err error
+
+		if filter.regexp, err = regexp.Compile(filter.value); err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
+
+func (p *selectorParser) parseHas() (selector, error) {
+	p.offset += len(":has(")
+	start := p.offset
+	depth := 1
+	quoted := false
+
+	for ; p.offset < len(p.query); p.offset++ {
+		switch p.query[p.offset] {
+		case '"':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted {
+				depth--
+			}
+		}
+
+		if depth == 0 {
+			break
+		}
+	}
+
+	if depth != 0 {
+		return selector{}, errors.New("\")\" expected")
+	}
+
+	end := p.offset
+	parser := selectorParser{query: p.query[start:end]}
+	has, err := parser.parse()
+
+	if err != nil {
+		p.offset = start + parser.offset
+
+		return has, err
+	}
+
+	p.offset = end + 1
+
+	return has, nil
+}
+
+func (p *selectorParser) skipSpaces() {
+	for p.offset < len(p.query) && strings.ContainsRune(" \t\n", rune(p.query[p.offset])) {
+		p.offset++
+	}
+}
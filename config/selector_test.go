@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf": "stream {\n    server {\n        listen 53 udp;\n        proxy_pass dns;\n    }\n}\nhttp {\n    include sites-enabled/*;\n}\n",
+		"sites-enabled/a.com": "server {\n    server_name a.com www.a.com;\n    listen 443 ssl;\n    location ~ ^/api {\n        proxy_pass http://api;\n    }\n" +
+			"    location /static {\n        root /var/www;\n    }\n}\n",
+		"sites-enabled/b.com": "server {\n    server_name b.com;\n    listen 80;\n    location /api/v2 {\n        include snippets/proxy.conf;\n    }\n}\n",
+		"snippets/proxy.conf": "proxy_pass http://backend;\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	nodes, err := config.Select("http > server[server_name=a.com] > location[~ ^/api] > proxy_pass")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	directive, ok := nodes[0].(*Directive)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"http://api"}, directive.GetValues())
+	assert.Equal(t, "http > server[a.com www.a.com] > location[~ ^/api]", directive.GetContextPath())
+
+	// descendants are matched across includes
+	nodes, err = config.Select("http proxy_pass")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "http > server[b.com] > location[/api/v2]", nodes[1].GetContextPath())
+
+	nodes, err = config.Select("proxy_pass")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 3)
+
+	nodes, err = config.Select("server:has(> listen[443 ssl])")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	serverBlock, ok := nodes[0].(*ServerBlock)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a.com", "www.a.com"}, serverBlock.GetServerNames())
+
+	nodes, err = config.Select("server:has(proxy_pass[^=http://back])")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, []string{"b.com"}, nodes[0].(*ServerBlock).GetServerNames())
+
+	nodes, err = config.Select("> stream > server")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+
+	nodes, err = config.Select("http location[^=/api]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	locationBlock, ok := nodes[0].(*LocationBlock)
+	assert.True(t, ok)
+	assert.Equal(t, "/api/v2", locationBlock.GetLocationMatch())
+
+	nodes, err = config.Select("server[server_name~=^www\\.] location[~=^/s] > *")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "root", nodes[0].GetName())
+
+	// location modifiers are followed by a prefix or a regular expression of the path
+	nodes, err = config.Select("location[~ ^/a]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "^/api", nodes[0].(*LocationBlock).GetLocationMatch())
+
+	nodes, err = config.Select("location[~ ~=^\\^/api$]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+
+	nodes, err = config.Select("location[= ^/api]")
+	assert.Nil(t, err)
+	assert.Empty(t, nodes)
+
+	nodes, err = config.Select("upstream")
+	assert.Nil(t, err)
+	assert.Empty(t, nodes)
+
+	for _, query := range []string{"", ">", "server[name=", "server:has(listen", "server:first", "location[~=(]"} {
+		_, err = config.Select(query)
+		assert.Truef(t, errors.Is(err, ErrInvalidSelector), "selector %q has to be invalid", query)
+	}
+}
+
+func TestSelectInBlock(t *testing.T) {
+	config := parseConfig(t)
+	serverBlocks := config.FindServerBlocksByServerName("example2.com")
+	assert.Len(t, serverBlocks, 1)
+
+	nodes, err := serverBlocks[0].Select("> server_name")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+
+	nodes, err = serverBlocks[0].Select("server_name[alias.example2.com]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+
+	configFile := config.GetConfigFile(example2ConfigFileName)
+	nodes, err = configFile.Select("server > server_name[^=www.]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, []string{"\"example2.com\"", "www.example2.com"}, nodes[0].(*Directive).GetValues())
+}
+
+func TestSelectRegexLocations(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf": "http {\n    server {\n        location ~ ^/api/v1 {\n            return 200;\n        }\n        location ~* \\.(png|jpg)$ {\n            expires 1d;\n        }\n" +
+			"        location ^~ /static/ {\n            root /var/www;\n        }\n        location /api/v2 {\n            return 200;\n        }\n    }\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	nodes, err := config.Select("location[~ ^/api]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "^/api/v1", nodes[0].(*LocationBlock).GetLocationMatch())
+
+	nodes, err = config.Select("location[~* ~=png]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+
+	nodes, err = config.Select("location[^~ /static]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+
+	// locations without a modifier
+	nodes, err = config.Select("location[^=/api]")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "/api/v2", nodes[0].(*LocationBlock).GetLocationMatch())
+}
@@ -139,5 +139,5 @@ func (s *ServerBlock) AddLocationBlock(modifier, match string, begining bool) Lo
 }
 
 func (s *ServerBlock) DeleteLocationBlock(locationBlock LocationBlock) {
-	deleteBlock(s.rawBlock, locationBlock.Block)
+	s.deleteBlock(locationBlock.Block)
 }
@@ -160,6 +160,21 @@ func TestDumpServerBlock(t *testing.T) {
 	assert.Equal(t, expectedContent, content)
 }
 
+func TestServerBlockPosition(t *testing.T) {
+	configFile := getConfigFile(t, example2ConfigFileName)
+	serverBlocks := configFile.FindServerBlocksByServerName("example2.com")
+	assert.Len(t, serverBlocks, 1)
+
+	position := serverBlocks[0].GetPosition()
+	assert.Equal(t, configFile.FilePath, position.FilePath)
+	assert.Equal(t, 21, position.Line)
+	assert.Equal(t, 1, position.Column)
+
+	comments := serverBlocks[0].FindComments()
+	assert.Equal(t, 21, comments[len(comments)-1].GetPosition().Line)
+	assert.Equal(t, 20, comments[len(comments)-2].GetPosition().Line)
+}
+
 func getServerBlockDirective(t *testing.T, serverName, directiveName string) (*Config, Directive) {
 	config, serverBlock := getServerBlock(t, serverName)
 	directives := serverBlock.FindDirectives(directiveName)
@@ -0,0 +1,382 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"golang.org/x/exp/maps"
+)
+
+var ErrSiteNotFound = errors.New("site not found")
+
+const (
+	sitesAvailableDir = "sites-available"
+	sitesEnabledDir   = "sites-enabled"
+)
+
+// Site is a virtual host of the Debian layout: a file in sites-available
+// enabled by a symbolic link in sites-enabled
+type Site struct {
+	Name    string
+	Enabled bool
+	// AvailablePath is the file in sites-available, it is empty for regular files in sites-enabled
+	AvailablePath string
+	// EnabledPath is the link or the file in sites-enabled, it is empty for disabled sites
+	EnabledPath string
+	// ConfigFile of a disabled site is not a part of the configuration, it can be changed and dumped separately
+	ConfigFile   *ConfigFile
+	ServerBlocks []ServerBlock
+	ServerNames  []string
+}
+
+// ListSites returns sites of sites-available and sites-enabled directories sorted by name.
+// Links in sites-enabled are matched with sites by their targets, so a link may have a different name.
+func (c *Config) ListSites() ([]Site, error) {
+	sites := make(map[string]*Site)
+
+	availablePaths, err := c.getSiteFiles(sitesAvailableDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, availablePath := range availablePaths {
+		name := filepath.Base(availablePath)
+		sites[name] = &Site{Name: name, AvailablePath: availablePath}
+	}
+
+	enabledPaths, err := c.getSiteFiles(sitesEnabledDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, enabledPath := range enabledPaths {
+		availablePath := c.getSiteAvailablePath(enabledPath)
+		name := filepath.Base(enabledPath)
+
+		if availablePath != "" {
+			name = filepath.Base(availablePath)
+		}
+
+		site, ok := sites[name]
+
+		if !ok || site.Enabled {
+			// a regular file or a second link to the same site
+			name = filepath.Base(enabledPath)
+			site = &Site{Name: name, AvailablePath: availablePath}
+			sites[enabledPath] = site
+		}
+
+		site.Enabled = true
+		site.EnabledPath = enabledPath
+	}
+
+	var result []Site
+
+	for _, site := range sites {
+		if err := c.loadSite(site); err != nil {
+			return nil, err
+		}
+
+		result = append(result, *site)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Name == result[j].Name {
+			return result[i].EnabledPath < result[j].EnabledPath
+		}
+
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// EnableSite links the site from sites-available into sites-enabled, the link is created on Dump.
+// The site becomes a part of the configuration if it matches an include directive.
+func (c *Config) EnableSite(name string) (*ConfigFile, error) {
+	availablePath := filepath.Join(c.serverRoot, sitesAvailableDir, name)
+	enabledPath := filepath.Join(c.serverRoot, sitesEnabledDir, name)
+
+	if tree, ok := c.parsedFiles[enabledPath]; ok {
+		return &ConfigFile{FilePath: enabledPath, configFile: tree, config: c}, nil
+	}
+
+	if c.fileExists(enabledPath) && !c.removedSymlinks[enabledPath] {
+		return nil, fmt.Errorf("%s already exists", enabledPath)
+	}
+
+	content, err := c.fileSystem.ReadFile(availablePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := c.rawParser.Parse(enabledPath, string(content))
+
+	if err != nil {
+		return nil, newParseError(availablePath, string(content), err)
+	}
+
+	if c.removedSymlinks[enabledPath] {
+		delete(c.removedSymlinks, enabledPath)
+	} else {
+		if c.newSymlinks == nil {
+			c.newSymlinks = make(map[string]string)
+		}
+
+		c.newSymlinks[enabledPath] = filepath.Join("..", sitesAvailableDir, name)
+	}
+
+	c.parsedFiles[enabledPath] = tree
+	c.recordFileState(enabledPath, content)
+
+	filePaths := maps.Keys(c.parsedFiles)
+	sort.Strings(filePaths)
+
+	for _, filePath := range filePaths {
+		if filePath != enabledPath {
+			c.addIncludeEdges(filePath, c.parsedFiles[filePath], enabledPath)
+		}
+	}
+
+	if err := c.parseIncludes(enabledPath, tree, []string{enabledPath}); err != nil {
+		return nil, err
+	}
+
+	return &ConfigFile{FilePath: enabledPath, configFile: tree, config: c}, nil
+}
+
+// addIncludeEdges adds edges of include directives of the file matching the included file like parseIncludes does
+func (c *Config) addIncludeEdges(filePath string, container entryContainer, includedPath string) {
+	for _, entry := range container.GetEntries() {
+		if entry.BlockDirective != nil {
+			c.addIncludeEdges(filePath, entry.BlockDirective, includedPath)
+
+			continue
+		}
+
+		if entry.Directive == nil || strings.ToLower(entry.GetIdentifier()) != "include" {
+			continue
+		}
+
+		includePath := c.getAbsPath(entry.Directive.GetFirstValueStr())
+
+		if matched, _ := filepath.Match(includePath, includedPath); matched {
+			c.includeEdges = append(c.includeEdges, IncludeEdge{
+				From:     filePath,
+				To:       includedPath,
+				Position: newPosition(entry.Directive.Pos),
+				Directive: Directive{
+					rawDirective: entry.Directive,
+					container:    container,
+					config:       c,
+					filePath:     filePath,
+				},
+			})
+		}
+	}
+}
+
+// DisableSite removes links of the site from sites-enabled on Dump. The site is found by its name in
+// sites-available like in ListSites, so links with a different name are removed too. Regular files
+// in sites-enabled are not removed, RemoveConfigFile can be used for them.
+func (c *Config) DisableSite(name string) error {
+	enabledPaths, err := c.getSiteEnabledPaths(name)
+
+	if err != nil {
+		return err
+	}
+
+	if len(enabledPaths) == 0 {
+		if c.fileExists(filepath.Join(c.serverRoot, sitesAvailableDir, name)) {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s", ErrSiteNotFound, name)
+	}
+
+	for _, enabledPath := range enabledPaths {
+		if _, ok := c.newSymlinks[enabledPath]; ok {
+			continue
+		}
+
+		stat, err := c.fileSystem.Lstat(enabledPath)
+
+		if err != nil {
+			return err
+		}
+
+		if stat.Mode()&fs.ModeSymlink == 0 {
+			return fmt.Errorf("%s is not a symbolic link", enabledPath)
+		}
+
+		if c.modifiedFiles[enabledPath] {
+			return fmt.Errorf("site %s has changes that are not dumped", name)
+		}
+	}
+
+	for _, enabledPath := range enabledPaths {
+		c.disableSiteLink(enabledPath)
+	}
+
+	return nil
+}
+
+func (c *Config) disableSiteLink(enabledPath string) {
+	if _, ok := c.newSymlinks[enabledPath]; ok {
+		delete(c.newSymlinks, enabledPath)
+	} else {
+		if c.removedSymlinks == nil {
+			c.removedSymlinks = make(map[string]bool)
+		}
+
+		c.removedSymlinks[enabledPath] = true
+	}
+
+	delete(c.parsedFiles, enabledPath)
+	delete(c.modifiedFiles, enabledPath)
+
+	var edges []IncludeEdge
+
+	for _, edge := range c.includeEdges {
+		if edge.From != enabledPath && edge.To != enabledPath {
+			edges = append(edges, edge)
+		}
+	}
+
+	c.includeEdges = edges
+}
+
+// getSiteEnabledPaths returns links pointing to the site and a file in sites-enabled with the name of the site
+func (c *Config) getSiteEnabledPaths(name string) ([]string, error) {
+	files, err := c.getSiteFiles(sitesEnabledDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var enabledPaths []string
+
+	for _, enabledPath := range files {
+		availablePath := c.getSiteAvailablePath(enabledPath)
+
+		if (availablePath != "" && filepath.Base(availablePath) == name) || filepath.Base(enabledPath) == name {
+			enabledPaths = append(enabledPaths, enabledPath)
+		}
+	}
+
+	return enabledPaths, nil
+}
+
+// getSiteFiles returns files of the directory on disk and in memory taking pending links into account
+func (c *Config) getSiteFiles(dir string) ([]string, error) {
+	pattern := filepath.Join(c.serverRoot, dir, "*")
+	files, err := c.fileSystem.Glob(pattern)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var siteFiles []string
+
+	for _, file := range files {
+		if !c.removedSymlinks[file] && !c.removedFiles[file] && !strings.HasSuffix(file, backupFileExtension) {
+			siteFiles = append(siteFiles, file)
+		}
+	}
+
+	for file := range c.parsedFiles {
+		if matched, _ := filepath.Match(pattern, file); matched && !c.fileExists(file) {
+			siteFiles = append(siteFiles, file)
+		}
+	}
+
+	sort.Strings(siteFiles)
+
+	return siteFiles, nil
+}
+
+// getSiteAvailablePath returns the file in sites-available the link points to
+func (c *Config) getSiteAvailablePath(enabledPath string) string {
+	target, ok := c.newSymlinks[enabledPath]
+
+	if !ok {
+		var err error
+
+		if target, err = c.fileSystem.ReadLink(enabledPath); err != nil {
+			return ""
+		}
+	}
+
+	targetPath := getSymlinkTargetPath(enabledPath, target)
+
+	if filepath.Dir(targetPath) != filepath.Join(c.serverRoot, sitesAvailableDir) {
+		return ""
+	}
+
+	return targetPath
+}
+
+func (c *Config) loadSite(site *Site) error {
+	if tree, ok := c.parsedFiles[site.EnabledPath]; site.Enabled && ok {
+		site.ConfigFile = &ConfigFile{FilePath: site.EnabledPath, configFile: tree, config: c}
+	} else {
+		filePath := site.AvailablePath
+
+		if site.Enabled {
+			filePath = site.EnabledPath
+		}
+
+		configFile, err := c.loadDetachedConfigFile(filePath)
+
+		if err != nil {
+			return err
+		}
+
+		site.ConfigFile = configFile
+	}
+
+	site.ServerBlocks = site.ConfigFile.FindServerBlocks()
+	site.ServerNames = []string{}
+
+	for _, serverBlock := range site.ServerBlocks {
+		site.ServerNames = append(site.ServerNames, serverBlock.GetServerNames()...)
+	}
+
+	return nil
+}
+
+// loadDetachedConfigFile parses the file without adding it to the configuration
+func (c *Config) loadDetachedConfigFile(filePath string) (*ConfigFile, error) {
+	config := &Config{
+		rawParser:   c.rawParser,
+		fileSystem:  c.fileSystem,
+		serverRoot:  c.serverRoot,
+		parsedFiles: make(map[string]*rawparser.Config),
+	}
+
+	tree, err := config.parseFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config.parsedFiles[filePath] = tree
+
+	return &ConfigFile{FilePath: filePath, configFile: tree, config: config}, nil
+}
+
+func getSymlinkTargetPath(linkPath, target string) string {
+	if filepath.IsAbs(target) {
+		return target
+	}
+
+	return filepath.Join(filepath.Dir(linkPath), target)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSites(t *testing.T) {
+	serverRoot := writeSitesConfig(t)
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	sites, err := config.ListSites()
+	assert.Nil(t, err)
+	assert.Len(t, sites, 3)
+
+	assert.Equal(t, "default", sites[0].Name)
+	assert.True(t, sites[0].Enabled)
+	assert.Empty(t, sites[0].AvailablePath)
+	assert.Len(t, sites[0].ServerBlocks, 1)
+
+	assert.Equal(t, "example.com", sites[1].Name)
+	assert.True(t, sites[1].Enabled)
+	assert.Equal(t, filepath.Join(serverRoot, "sites-available/example.com"), sites[1].AvailablePath)
+	assert.Equal(t, filepath.Join(serverRoot, "sites-enabled/example"), sites[1].EnabledPath)
+	assert.Equal(t, []string{"example.com"}, sites[1].ServerNames)
+
+	assert.Equal(t, "test.com", sites[2].Name)
+	assert.False(t, sites[2].Enabled)
+	assert.Empty(t, sites[2].EnabledPath)
+	assert.Equal(t, []string{"test.com", "www.test.com"}, sites[2].ServerNames)
+
+	// disabled sites are not a part of the configuration
+	assert.Empty(t, config.FindServerBlocksByServerName("test.com"))
+}
+
+func TestEnableAndDisableSite(t *testing.T) {
+	serverRoot := writeSitesConfig(t)
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	configFile, err := config.EnableSite("test.com")
+	assert.Nil(t, err)
+	assert.Len(t, config.FindServerBlocksByServerName("test.com"), 1)
+	assert.Len(t, config.FindHttpBlocks()[0].FindServerBlocks(), 3)
+	assert.Len(t, configFile.IncludedBy(), 1)
+
+	serverBlocks := configFile.FindServerBlocks()
+	assert.Len(t, serverBlocks, 1)
+	serverBlocks[0].AddDirective(NewDirective("listen", []string{"443", "ssl"}), false, false)
+
+	sites, err := config.ListSites()
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", sites[1].Name)
+
+	// the link has a different name than the site
+	err = config.DisableSite(sites[1].Name)
+	assert.Nil(t, err)
+	assert.Empty(t, config.FindServerBlocksByServerName("example.com"))
+
+	err = config.DisableSite("example.com")
+	assert.Nil(t, err)
+
+	err = config.DisableSite("default")
+	assert.NotNil(t, err)
+
+	err = config.DisableSite("nope")
+	assert.True(t, errors.Is(err, ErrSiteNotFound))
+
+	err = config.Dump()
+	assert.Nil(t, err)
+
+	target, err := os.Readlink(filepath.Join(serverRoot, "sites-enabled/test.com"))
+	assert.Nil(t, err)
+	assert.Equal(t, "../sites-available/test.com", target)
+
+	content, err := os.ReadFile(filepath.Join(serverRoot, "sites-available/test.com"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "listen 443 ssl;")
+
+	_, err = os.Lstat(filepath.Join(serverRoot, "sites-enabled/example"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(serverRoot, "sites-available/example.com"))
+	assert.Nil(t, err)
+
+	config, err = GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	sites, err = config.ListSites()
+	assert.Nil(t, err)
+	assert.Len(t, sites, 3)
+	assert.False(t, sites[1].Enabled)
+	assert.True(t, sites[2].Enabled)
+	assert.Len(t, config.FindServerBlocksByServerName("test.com"), 1)
+}
+
+func TestEnableSiteIncludedBySharedSnippet(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":               "http {\n    include snippets/sites.conf;\n}\nhttp {\n    include snippets/sites.conf;\n}\n",
+		"snippets/sites.conf":      "include sites-enabled/*;\n",
+		"sites-available/test.com": "server {\n    server_name test.com;\n}\n",
+		"sites-enabled/default":    "server {\n    listen 80 default_server;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	defaultConfigFile := config.GetConfigFile("default")
+	assert.NotNil(t, defaultConfigFile)
+
+	configFile, err := config.EnableSite("test.com")
+	assert.Nil(t, err)
+
+	// the same edges as for sites loaded with the configuration
+	edges := configFile.IncludedBy()
+	assert.Len(t, edges, len(defaultConfigFile.IncludedBy()))
+	assert.Len(t, edges, 1)
+	assert.Equal(t, filepath.Join(serverRoot, "snippets/sites.conf"), edges[0].From)
+	assert.Equal(t, 1, edges[0].Position.Line)
+}
+
+func writeSitesConfig(t *testing.T) string {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":                  "http {\n    include sites-enabled/*;\n}\n",
+		"sites-available/example.com": "server {\n    server_name example.com;\n}\n",
+		"sites-available/test.com":    "server {\n    server_name test.com www.test.com;\n}\n",
+		"sites-enabled/default":       "server {\n    listen 80 default_server;\n}\n",
+	})
+
+	err := os.Symlink("../sites-available/example.com", filepath.Join(serverRoot, "sites-enabled/example"))
+	assert.Nil(t, err)
+
+	return serverRoot
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var ErrTransactionDone = errors.New("transaction is already committed or rolled back")
+
+// Transaction groups in-memory changes of a configuration, see Config.Begin
+type Transaction struct {
+	config   *Config
+	snapshot *rawparser.Snapshot
+	state    configState
+	done     bool
+	// dumpedFiles keeps states of files written by Dump inside the transaction,
+	// the state is nil if the file was removed or renamed
+	dumpedFiles map[string]*fileState
+}
+
+// configState contains everything a transaction restores besides trees
+type configState struct {
+	parsedFiles     map[string]*rawparser.Config
+	modifiedFiles   map[string]bool
+	newFiles        map[string]bool
+	removedFiles    map[string]bool
+	renamedFiles    map[string]string
+	movedFiles      map[string]string
+	newSymlinks     map[string]string
+	removedSymlinks map[string]bool
+	fileStates      map[string]fileState
+	includeEdges    []IncludeEdge
+	warnings        []error
+	extraFiles      []string
+	edits           []Edit
+	undoneEdits     []Edit
+}
+
+// Begin starts a transaction. Rollback restores all trees and files of the configuration as they were
+// when the transaction began, objects found before stay valid. Changes made on disk by Dump inside
+// the transaction are not reverted, such files are marked as modified to be written again on the next Dump.
+// Files re-read by Reload or Rebase inside the transaction are treated as changed on disk again.
+func (c *Config) Begin() *Transaction {
+	snapshot := rawparser.NewSnapshot()
+
+	for _, tree := range c.parsedFiles {
+		snapshot.Add(tree)
+	}
+
+	transaction := &Transaction{
+		config:      c,
+		snapshot:    snapshot,
+		dumpedFiles: make(map[string]*fileState),
+		state: configState{
+			parsedFiles:     maps.Clone(c.parsedFiles),
+			modifiedFiles:   maps.Clone(c.modifiedFiles),
+			newFiles:        maps.Clone(c.newFiles),
+			removedFiles:    maps.Clone(c.removedFiles),
+			renamedFiles:    maps.Clone(c.renamedFiles),
+			movedFiles:      maps.Clone(c.movedFiles),
+			newSymlinks:     maps.Clone(c.newSymlinks),
+			removedSymlinks: maps.Clone(c.removedSymlinks),
+			fileStates:      maps.Clone(c.fileStates),
+			includeEdges:    slices.Clone(c.includeEdges),
+			warnings:        slices.Clone(c.warnings),
+			extraFiles:      slices.Clone(c.extraFiles),
+			edits:           slices.Clone(c.edits),
+			undoneEdits:     slices.Clone(c.undoneEdits),
+		},
+	}
+	c.transactions = append(c.transactions, transaction)
+
+	return transaction
+}
+
+// Commit keeps the changes, they are written by the next Dump
+func (t *Transaction) Commit() error {
+	if t.done {
+		return ErrTransactionDone
+	}
+
+	t.end()
+
+	return nil
+}
+
+// CommitAndDump writes the changes and ends the transaction. If Dump fails the transaction stays active,
+// so the changes can still be rolled back.
+func (t *Transaction) CommitAndDump(options DumpOptions) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+
+	if err := t.config.DumpWithOptions(options); err != nil {
+		return err
+	}
+
+	t.end()
+
+	return nil
+}
+
+// Rollback discards all in-memory changes made since the transaction began
+func (t *Transaction) Rollback() error {
+	if t.done {
+		return ErrTransactionDone
+	}
+
+	t.end()
+	t.snapshot.Restore()
+
+	c := t.config
+	c.parsedFiles = t.state.parsedFiles
+	c.modifiedFiles = t.state.modifiedFiles
+	c.newFiles = t.state.newFiles
+	c.removedFiles = t.state.removedFiles
+	c.renamedFiles = t.state.renamedFiles
+	c.movedFiles = t.state.movedFiles
+	c.newSymlinks = t.state.newSymlinks
+	c.removedSymlinks = t.state.removedSymlinks
+	c.fileStates = t.state.fileStates
+	c.includeEdges = t.state.includeEdges
+	c.warnings = t.state.warnings
+	c.extraFiles = t.state.extraFiles
+	c.edits = t.state.edits
+	c.undoneEdits = t.state.undoneEdits
+
+	// files written by Dump inside the transaction keep their new state on disk, states of files
+	// re-read by Reload or Rebase are restored, so that changes made on disk are still detected by Dump
+	for filePath, state := range t.dumpedFiles {
+		if c.fileStates == nil {
+			c.fileStates = make(map[string]fileState)
+		}
+
+		if state != nil {
+			c.fileStates[filePath] = *state
+		} else {
+			delete(c.fileStates, filePath)
+		}
+
+		if _, ok := c.parsedFiles[filePath]; ok {
+			c.markModified(filePath)
+		}
+	}
+
+	return nil
+}
+
+func (t *Transaction) end() {
+	t.done = true
+	t.config.transactions = slices.DeleteFunc(t.config.transactions, func(transaction *Transaction) bool {
+		return transaction == t
+	})
+}
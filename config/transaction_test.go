@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	serverBlocks := config.FindServerBlocksByServerName("example.com")
+	assert.Len(t, serverBlocks, 1)
+	serverNames := config.FindDirectives("server_name")
+	assert.Len(t, serverNames, 1)
+
+	transaction := config.Begin()
+
+	httpBlock := config.FindHttpBlocks()[0]
+	httpBlock.AddUpstreamBlock("backend", true)
+	serverBlock := httpBlock.AddServerBlock()
+	serverBlock.AddDirective(NewDirective("server_name", []string{"new.example.com"}), false, true)
+	serverBlocks[0].AddLocationBlock("", "/api", false)
+	serverNames[0].SetValues([]string{"changed.example.com"})
+	_, err = config.AddConfigFile("/etc/nginx/conf.d/new.conf")
+	assert.Nil(t, err)
+
+	diffs, err := config.Diff()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, diffs)
+
+	err = transaction.Rollback()
+	assert.Nil(t, err)
+
+	diffs, err = config.Diff()
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+	assert.Empty(t, config.ModifiedFiles())
+	assert.Nil(t, config.GetConfigFile("new.conf"))
+	assert.Empty(t, config.FindUpstreamBlocks())
+	assert.Len(t, config.FindServerBlocks(), 1)
+	assert.Len(t, serverBlocks[0].FindLocationBlocks(), 1)
+	assert.Equal(t, []string{"example.com"}, serverNames[0].GetValues())
+
+	// objects found before the transaction stay usable
+	serverNames[0].SetValue("www.example.com")
+	assert.Equal(t, []string{"/etc/nginx/sites-enabled/example.com.conf"}, config.ModifiedFiles())
+
+	err = transaction.Rollback()
+	assert.True(t, errors.Is(err, ErrTransactionDone))
+	err = transaction.Commit()
+	assert.True(t, errors.Is(err, ErrTransactionDone))
+}
+
+func TestTransactionCommit(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	transaction := config.Begin()
+	config.FindDirectives("server_name")[0].SetValue("changed.example.com")
+
+	err = transaction.CommitAndDump(DumpOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data), "server_name changed.example.com;")
+	assert.Empty(t, config.ModifiedFiles())
+
+	err = transaction.Rollback()
+	assert.True(t, errors.Is(err, ErrTransactionDone))
+
+	transaction = config.Begin()
+	config.FindDirectives("server_name")[0].SetValue("other.example.com")
+	err = config.Dump()
+	assert.Nil(t, err)
+
+	// the file written inside the transaction has to be written again with the restored content
+	err = transaction.Rollback()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/etc/nginx/sites-enabled/example.com.conf"}, config.ModifiedFiles())
+
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.Contains(t, string(fsys.MapFS["etc/nginx/sites-enabled/example.com.conf"].Data), "server_name changed.example.com;")
+}
+
+func TestTransactionRollbackAfterReload(t *testing.T) {
+	fsys := writableMapFS{MapFS: getTestMapFS()}
+
+	config, err := GetConfigFromFS(fsys, "/etc/nginx", "", false)
+	assert.Nil(t, err)
+
+	transaction := config.Begin()
+
+	fsys.MapFS["etc/nginx/snippets/fastcgi.conf"].Data = []byte("fastcgi_param HTTPS on;\n")
+	_, err = config.Reload()
+	assert.Nil(t, err)
+
+	err = transaction.Rollback()
+	assert.Nil(t, err)
+	assert.Empty(t, config.ModifiedFiles())
+
+	// the file changed on disk is not overwritten with the restored tree
+	err = config.Dump()
+	assert.Nil(t, err)
+	assert.Equal(t, "fastcgi_param HTTPS on;\n", string(fsys.MapFS["etc/nginx/snippets/fastcgi.conf"].Data))
+
+	config.GetConfigFile("fastcgi.conf").AddDirective(NewDirective("fastcgi_index", []string{"index.php"}), false, true)
+
+	var conflictErr *ConflictError
+	err = config.Dump()
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []string{"/etc/nginx/snippets/fastcgi.conf"}, conflictErr.FilePaths)
+	assert.Equal(t, "fastcgi_param HTTPS on;\n", string(fsys.MapFS["etc/nginx/snippets/fastcgi.conf"].Data))
+}
@@ -23,7 +23,7 @@ func (b *UpstreamBlock) SetUpstreamName(name string) {
 		parameters[0] = name
 	}
 
-	b.rawBlock.SetParameters(parameters)
+	b.SetParameters(parameters)
 }
 
 func (b *UpstreamBlock) GetServers() []UpstreamServer {
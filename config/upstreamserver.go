@@ -24,18 +24,18 @@ func (s *UpstreamServer) SetAddress(address string) {
 	values := s.rawDirective.GetExpressions()
 	values[0] = address
 
-	s.rawDirective.SetValues(values)
+	s.SetValues(values)
 }
 
 func (s *UpstreamServer) SetFlags(flags []string) {
 	values := s.rawDirective.GetExpressions()
 
 	if len(values) == 0 {
-		s.rawDirective.SetValues(flags)
+		s.SetValues(flags)
 	} else {
 		values = []string{values[0]}
 		values = append(values, flags...)
-		s.rawDirective.SetValues(values)
+		s.SetValues(values)
 	}
 }
 
@@ -0,0 +1,320 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r2dtools/gonginxconf/internal/rawdumper"
+	"github.com/r2dtools/gonginxconf/internal/rawparser"
+)
+
+const DefaultWatchInterval = 2 * time.Second
+
+type EventOp string
+
+const (
+	EventAdded   EventOp = "added"
+	EventChanged EventOp = "changed"
+	EventRemoved EventOp = "removed"
+	// EventConflict is emitted by Poll for files changed both on disk and in memory, see Rebase
+	EventConflict EventOp = "conflict"
+)
+
+type EventKind string
+
+const (
+	EventFile      EventKind = "file"
+	EventBlock     EventKind = "block"
+	EventDirective EventKind = "directive"
+)
+
+// Event describes a change of the configuration made on disk
+type Event struct {
+	Op       EventOp
+	Kind     EventKind
+	FilePath string
+	// Context is the path of the block containing the entry, e.g. "http > server[example.com]"
+	Context string
+	// Name is the name of the block or directive
+	Name string
+	// Parameters are parameters of the block or values of the directive,
+	// server names for server blocks
+	Parameters []string
+	// OldValue and NewValue contain the entry as it was and as it is now
+	OldValue string
+	NewValue string
+}
+
+// String returns a human readable description, e.g. "server block for example.com added in /etc/nginx/sites-enabled/example.com"
+func (e Event) String() string {
+	switch e.Kind {
+	case EventFile:
+		if e.Op == EventConflict {
+			return fmt.Sprintf("file %s changed on disk and in memory", e.FilePath)
+		}
+
+		return fmt.Sprintf("file %s %s", e.FilePath, e.Op)
+	case EventBlock:
+		description := fmt.Sprintf("%s block", e.Name)
+
+		if e.Name == serverBlockName && len(e.Parameters) != 0 {
+			description = fmt.Sprintf("server block for %s", strings.Join(e.Parameters, " "))
+		} else if len(e.Parameters) != 0 {
+			description = fmt.Sprintf("%s block %s", e.Name, strings.Join(e.Parameters, " "))
+		}
+
+		return fmt.Sprintf("%s %s in %s", description, e.Op, e.FilePath)
+	}
+
+	return fmt.Sprintf("directive %s %s in %s", e.Name, e.Op, e.FilePath)
+}
+
+// Watcher polls files of the configuration and reports changes made on disk as events.
+// Files matched by include masks later are picked up as well. The watcher polls its own copy
+// of the configuration, the configuration passed to NewWatcher is never changed by it,
+// so Reload has to be called to apply changes made on disk, inside Update if the configuration
+// is shared between goroutines.
+type Watcher struct {
+	Events chan Event
+	Errors chan error
+
+	// config is the copy of the watched configuration used by the watcher only
+	config   *Config
+	interval time.Duration
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// NewWatcher loads a copy of the configuration from disk and starts polling it with the interval,
+// DefaultWatchInterval is used if it is zero. Changes made on disk after the watcher is created are reported.
+// Events are delivered in batches of a single poll, the channels are closed by Close.
+// The configuration is only read by NewWatcher, call it inside View if the configuration is shared.
+func NewWatcher(config *Config, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	watchedConfig, err := config.loadCopy()
+
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := &Watcher{
+		Events:   make(chan Event),
+		Errors:   make(chan error),
+		config:   watchedConfig,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+	watcher.wg.Add(1)
+
+	go watcher.run()
+
+	return watcher, nil
+}
+
+// Close stops polling and closes the channels
+func (w *Watcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+		close(w.Events)
+		close(w.Errors)
+	})
+
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		events, err := w.config.Poll()
+
+		if err != nil {
+			select {
+			case w.Errors <- err:
+			case <-w.done:
+				return
+			}
+
+			continue
+		}
+
+		for _, event := range events {
+			select {
+			case w.Events <- event:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// loadCopy parses files of the configuration from disk again, the copy shares no objects with c
+func (c *Config) loadCopy() (*Config, error) {
+	configCopy, err := getConfig(c.fileSystem, c.serverRoot, c.configRoot, c.quiteMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, extraFile := range c.extraFiles {
+		if err := configCopy.ParseFile(extraFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return configCopy, nil
+}
+
+// Poll reloads the configuration once and returns changes made on disk since the last reload
+func (c *Config) Poll() ([]Event, error) {
+	previousFiles := make(map[string]*rawparser.Config, len(c.parsedFiles))
+
+	for filePath, tree := range c.parsedFiles {
+		previousFiles[filePath] = tree
+	}
+
+	result, err := c.Reload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	differ := treeDiffer{dumper: &rawdumper.RawDumper{}}
+
+	for _, filePath := range result.Removed {
+		differ.addFileEvent(EventRemoved, filePath)
+		differ.diff(filePath, nil, previousFiles[filePath].GetEntries(), nil)
+	}
+
+	for _, filePath := range result.Added {
+		differ.addFileEvent(EventAdded, filePath)
+		differ.diff(filePath, nil, nil, c.parsedFiles[filePath].GetEntries())
+	}
+
+	for _, filePath := range result.Changed {
+		differ.addFileEvent(EventChanged, filePath)
+		differ.diff(filePath, nil, previousFiles[filePath].GetEntries(), c.parsedFiles[filePath].GetEntries())
+	}
+
+	for _, filePath := range result.Conflicts {
+		differ.addFileEvent(EventConflict, filePath)
+	}
+
+	return differ.events, nil
+}
+
+type treeDiffer struct {
+	dumper *rawdumper.RawDumper
+	events []Event
+}
+
+func (d *treeDiffer) diff(filePath string, context []string, oldEntries, newEntries []*rawparser.Entry) {
+	repeatable := getRepeatableIdentifiers(oldEntries, newEntries)
+	oldMergeEntries := getMergeEntries(oldEntries, repeatable)
+	newMergeEntries := getMergeEntries(newEntries, repeatable)
+	oldMap := getMergeEntriesMap(oldMergeEntries)
+	newMap := getMergeEntriesMap(newMergeEntries)
+
+	for _, oldEntry := range oldMergeEntries {
+		if _, ok := newMap[oldEntry.key]; !ok {
+			d.addEntryEvent(EventRemoved, filePath, context, oldEntry.entry, nil)
+		}
+	}
+
+	for _, newEntry := range newMergeEntries {
+		oldEntry, ok := oldMap[newEntry.key]
+
+		if !ok {
+			d.addEntryEvent(EventAdded, filePath, context, nil, newEntry.entry)
+
+			continue
+		}
+
+		if d.dumper.DumpEntry(oldEntry.entry) == d.dumper.DumpEntry(newEntry.entry) {
+			continue
+		}
+
+		if oldEntry.entry.BlockDirective != nil && newEntry.entry.BlockDirective != nil {
+			blockContext := append(append([]string{}, context...), getBlockContextName(newEntry.entry.BlockDirective))
+			d.diff(filePath, blockContext, oldEntry.entry.BlockDirective.GetEntries(), newEntry.entry.BlockDirective.GetEntries())
+
+			continue
+		}
+
+		d.addEntryEvent(EventChanged, filePath, context, oldEntry.entry, newEntry.entry)
+	}
+}
+
+func (d *treeDiffer) addFileEvent(op EventOp, filePath string) {
+	d.events = append(d.events, Event{Op: op, Kind: EventFile, FilePath: filePath})
+}
+
+// addEntryEvent reports blocks and directives, comments are skipped
+func (d *treeDiffer) addEntryEvent(op EventOp, filePath string, context []string, oldEntry, newEntry *rawparser.Entry) {
+	entry := newEntry
+
+	if entry == nil {
+		entry = oldEntry
+	}
+
+	if entry.Comment != nil {
+		return
+	}
+
+	event := Event{
+		Op:       op,
+		FilePath: filePath,
+		Context:  strings.Join(context, " > "),
+		Name:     entry.GetIdentifier(),
+	}
+
+	if entry.BlockDirective != nil {
+		event.Kind = EventBlock
+		event.Parameters = entry.BlockDirective.GetParametersExpressions()
+
+		if entry.BlockDirective.Identifier == serverBlockName {
+			event.Parameters = getServerNames(entry.BlockDirective)
+		}
+	} else {
+		event.Kind = EventDirective
+		event.Parameters = entry.Directive.GetExpressions()
+	}
+
+	if oldEntry != nil {
+		event.OldValue = strings.TrimSpace(d.dumper.DumpEntry(oldEntry))
+	}
+
+	if newEntry != nil {
+		event.NewValue = strings.TrimSpace(d.dumper.DumpEntry(newEntry))
+	}
+
+	d.events = append(d.events, event)
+}
+
+func getServerNames(block *rawparser.BlockDirective) []string {
+	var serverNames []string
+
+	for _, entry := range block.GetEntriesByIdentifier("server_name") {
+		if entry.Directive != nil {
+			serverNames = append(serverNames, entry.Directive.GetExpressions()...)
+		}
+	}
+
+	return serverNames
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slices"
+)
+
+func TestPoll(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":    "http {\n    include conf.d/*.conf;\n}\n",
+		"conf.d/a.conf": "server {\n    server_name a.com;\n    ssl_certificate /etc/ssl/a.crt;\n}\n",
+	})
+	aConfigPath := filepath.Join(serverRoot, "conf.d/a.conf")
+	bConfigPath := filepath.Join(serverRoot, "conf.d/b.conf")
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	events, err := config.Poll()
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+
+	err = os.WriteFile(aConfigPath, []byte("server {\n    server_name a.com;\n    ssl_certificate /etc/letsencrypt/a.pem;\n}\n"), 0644)
+	assert.Nil(t, err)
+	err = os.WriteFile(bConfigPath, []byte("server {\n    server_name b.com;\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	events, err = config.Poll()
+	assert.Nil(t, err)
+	assert.Len(t, events, 4)
+
+	assert.Equal(t, Event{Op: EventAdded, Kind: EventFile, FilePath: bConfigPath}, events[0])
+	assert.Equal(t, EventBlock, events[1].Kind)
+	assert.Equal(t, []string{"b.com"}, events[1].Parameters)
+	assert.Equal(t, "server block for b.com added in "+bConfigPath, events[1].String())
+
+	assert.Equal(t, "file "+aConfigPath+" changed", events[2].String())
+	assert.Equal(t, EventChanged, events[3].Op)
+	assert.Equal(t, EventDirective, events[3].Kind)
+	assert.Equal(t, "server[a.com]", events[3].Context)
+	assert.Equal(t, "ssl_certificate /etc/ssl/a.crt;", events[3].OldValue)
+	assert.Equal(t, "ssl_certificate /etc/letsencrypt/a.pem;", events[3].NewValue)
+	assert.Equal(t, "directive ssl_certificate changed in "+aConfigPath, events[3].String())
+
+	err = os.Remove(bConfigPath)
+	assert.Nil(t, err)
+
+	events, err = config.Poll()
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "server block for b.com removed in "+bConfigPath, events[1].String())
+}
+
+func TestWatcher(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf": "http {\n    include conf.d/*.conf;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	watcher, err := NewWatcher(config, 10*time.Millisecond)
+	assert.Nil(t, err)
+	defer watcher.Close()
+
+	err = os.Mkdir(filepath.Join(serverRoot, "conf.d"), 0755)
+	assert.Nil(t, err)
+	err = os.WriteFile(filepath.Join(serverRoot, "conf.d/a.conf"), []byte("server {\n    server_name a.com;\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	var events []Event
+
+	for len(events) < 2 {
+		select {
+		case event := <-watcher.Events:
+			events = append(events, event)
+		case err := <-watcher.Errors:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no events received")
+		}
+	}
+
+	assert.Equal(t, EventFile, events[0].Kind)
+	assert.Equal(t, "server block for a.com added in "+filepath.Join(serverRoot, "conf.d/a.conf"), events[1].String())
+
+	err = watcher.Close()
+	assert.Nil(t, err)
+
+	_, ok := <-watcher.Events
+	assert.False(t, ok)
+}
+
+// run with -race, the watcher must not change the configuration used by the caller
+func TestWatcherWithPlainAPI(t *testing.T) {
+	serverRoot := writeConfigFiles(t, map[string]string{
+		"nginx.conf":    "http {\n    include conf.d/*.conf;\n}\n",
+		"conf.d/a.conf": "server {\n    server_name a.com;\n}\n",
+	})
+
+	config, err := GetConfig(serverRoot, "", false)
+	assert.Nil(t, err)
+
+	watcher, err := NewWatcher(config, time.Millisecond)
+	assert.Nil(t, err)
+	defer watcher.Close()
+
+	for iteration := 0; iteration < 20; iteration++ {
+		serverBlocks := config.FindServerBlocksByServerName("a.com")
+		assert.Len(t, serverBlocks, 1)
+		serverBlocks[0].AddDirective(NewDirective("listen", []string{fmt.Sprint(8000 + iteration)}), false, true)
+		assert.Nil(t, config.Dump())
+		time.Sleep(time.Millisecond)
+	}
+
+	err = os.WriteFile(filepath.Join(serverRoot, "conf.d/b.conf"), []byte("server {\n    server_name b.com;\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	for added := false; !added; {
+		select {
+		case event := <-watcher.Events:
+			added = event.Kind == EventBlock && event.Op == EventAdded && slices.Contains(event.Parameters, "b.com")
+		case err := <-watcher.Errors:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no events received")
+		}
+	}
+
+	// changes made on disk are applied by the caller
+	assert.Empty(t, config.FindServerBlocksByServerName("b.com"))
+	result, err := config.Reload()
+	assert.Nil(t, err)
+	assert.Empty(t, result.Conflicts)
+	assert.Len(t, config.FindServerBlocksByServerName("b.com"), 1)
+	assert.Len(t, config.FindDirectives("listen"), 20)
+}
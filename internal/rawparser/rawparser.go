@@ -20,15 +20,18 @@ type Entry struct {
 }
 
 type Comment struct {
+	Pos   lexer.Position
 	Value string `@Comment`
 }
 
 type Directive struct {
+	Pos        lexer.Position
 	Identifier string   `@Ident`
 	Values     []*Value `@@*";"`
 }
 
 type BlockDirective struct {
+	Pos        lexer.Position
 	Identifier string        `@Ident`
 	Parameters []*Value      `@@*`
 	Content    *BlockContent `"{" @@ "}"`
@@ -162,12 +165,90 @@ func (e *Entry) GetIdentifier() string {
 	return ""
 }
 
+func (e *Entry) GetPosition() lexer.Position {
+	if e.Directive != nil {
+		return e.Directive.Pos
+	}
+
+	if e.BlockDirective != nil {
+		return e.BlockDirective.Pos
+	}
+
+	if e.Comment != nil {
+		return e.Comment.Pos
+	}
+
+	return lexer.Position{}
+}
+
+func (e *Entry) Clone() *Entry {
+	clone := &Entry{
+		StartNewLines: append([]string(nil), e.StartNewLines...),
+		EndNewLines:   append([]string(nil), e.EndNewLines...),
+	}
+
+	if e.Comment != nil {
+		comment := *e.Comment
+		clone.Comment = &comment
+	}
+
+	if e.Directive != nil {
+		clone.Directive = e.Directive.Clone()
+	}
+
+	if e.BlockDirective != nil {
+		clone.BlockDirective = e.BlockDirective.Clone()
+	}
+
+	return clone
+}
+
+func (d *Directive) Clone() *Directive {
+	return &Directive{
+		Pos:        d.Pos,
+		Identifier: d.Identifier,
+		Values:     cloneValues(d.Values),
+	}
+}
+
+func (b *BlockDirective) Clone() *BlockDirective {
+	clone := &BlockDirective{
+		Pos:        b.Pos,
+		Identifier: b.Identifier,
+		Parameters: cloneValues(b.Parameters),
+	}
+
+	if b.Content != nil {
+		clone.Content = &BlockContent{
+			Entries: CloneEntries(b.Content.Entries),
+		}
+	}
+
+	return clone
+}
+
+func CloneEntries(entries []*Entry) []*Entry {
+	if entries == nil {
+		return nil
+	}
+
+	clones := make([]*Entry, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry != nil {
+			clones = append(clones, entry.Clone())
+		}
+	}
+
+	return clones
+}
+
 type RawParser struct {
 	participleParser *participle.Parser[Config]
 }
 
-func (p *RawParser) Parse(content string) (*Config, error) {
-	return p.participleParser.ParseString("", content)
+func (p *RawParser) Parse(filePath, content string) (*Config, error) {
+	return p.participleParser.ParseString(filePath, content)
 }
 
 func GetRawParser() (*RawParser, error) {
@@ -219,3 +300,19 @@ func getExpressions(values []*Value) []string {
 
 	return expressions
 }
+
+func cloneValues(values []*Value) []*Value {
+	if values == nil {
+		return nil
+	}
+
+	clones := make([]*Value, 0, len(values))
+
+	for _, value := range values {
+		if value != nil {
+			clones = append(clones, &Value{Expression: value.Expression})
+		}
+	}
+
+	return clones
+}
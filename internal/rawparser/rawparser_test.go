@@ -15,7 +15,7 @@ func TestParse(t *testing.T) {
 	content, err := os.ReadFile("../../test/nginx.conf")
 	assert.Nilf(t, err, "could not read config file")
 
-	parsedConfig, err := parser.Parse(string(content))
+	parsedConfig, err := parser.Parse("", string(content))
 	assert.Nilf(t, err, "could not parse config: %v", err)
 
 	expectedData := &Config{}
@@ -27,3 +27,45 @@ func TestParse(t *testing.T) {
 
 	assert.Equal(t, expectedData, parsedConfig, "parsed data is invalid")
 }
+
+func TestCloneEntries(t *testing.T) {
+	parser, err := GetRawParser()
+	assert.Nilf(t, err, "could not create parser: %v", err)
+
+	content, err := os.ReadFile("../../test/nginx.conf")
+	assert.Nilf(t, err, "could not read config file")
+
+	parsedConfig, err := parser.Parse("", string(content))
+	assert.Nilf(t, err, "could not parse config: %v", err)
+
+	entries := CloneEntries(parsedConfig.Entries)
+	assert.Equal(t, parsedConfig.Entries, entries)
+
+	entries[2].Directive.SetValues([]string{"nobody"})
+	assert.Equal(t, "www-data", parsedConfig.Entries[2].Directive.GetFirstValueStr())
+}
+
+func TestSnapshot(t *testing.T) {
+	parser, err := GetRawParser()
+	assert.Nilf(t, err, "could not create parser: %v", err)
+
+	parsedConfig, err := parser.Parse("", "user www-data;\nhttp {\n    server {\n        listen 80;\n    }\n}\n")
+	assert.Nilf(t, err, "could not parse config: %v", err)
+
+	original := CloneEntries(parsedConfig.Entries)
+	snapshot := NewSnapshot()
+	snapshot.Add(parsedConfig)
+
+	http := parsedConfig.Entries[1].BlockDirective
+	server := http.GetEntries()[0].BlockDirective
+	server.GetEntries()[0].Directive.Values[0].Expression = "443"
+	server.SetEntries(append(server.GetEntries(), &Entry{Directive: &Directive{Identifier: "root"}}))
+	http.SetParameters([]string{"invalid"})
+	parsedConfig.Entries[0].Directive.SetValues([]string{"nobody"})
+	parsedConfig.SetEntries(parsedConfig.Entries[1:])
+
+	snapshot.Restore()
+	assert.Equal(t, original, parsedConfig.Entries)
+	assert.Same(t, http, parsedConfig.Entries[1].BlockDirective)
+	assert.Len(t, server.GetEntries(), 1)
+}
@@ -0,0 +1,137 @@
+package rawparser
+
+import "golang.org/x/exp/slices"
+
+// Snapshot keeps the state of every node of trees. Restore writes the state back into the same nodes,
+// so pointers to nodes taken before the snapshot stay valid and nodes added later become detached.
+type Snapshot struct {
+	configs    map[*Config]Config
+	entries    map[*Entry]Entry
+	comments   map[*Comment]Comment
+	directives map[*Directive]Directive
+	blocks     map[*BlockDirective]BlockDirective
+	contents   map[*BlockContent]BlockContent
+	values     map[*Value]Value
+}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		configs:    make(map[*Config]Config),
+		entries:    make(map[*Entry]Entry),
+		comments:   make(map[*Comment]Comment),
+		directives: make(map[*Directive]Directive),
+		blocks:     make(map[*BlockDirective]BlockDirective),
+		contents:   make(map[*BlockContent]BlockContent),
+		values:     make(map[*Value]Value),
+	}
+}
+
+func (s *Snapshot) Add(config *Config) {
+	if config == nil {
+		return
+	}
+
+	s.configs[config] = Config{Entries: slices.Clone(config.Entries)}
+	s.addEntries(config.Entries)
+}
+
+// AddBlock keeps the state of the block and all nodes inside it
+func (s *Snapshot) AddBlock(block *BlockDirective) {
+	if block == nil {
+		return
+	}
+
+	s.addBlock(block)
+}
+
+func (s *Snapshot) Restore() {
+	for config, state := range s.configs {
+		*config = state
+		config.Entries = slices.Clone(state.Entries)
+	}
+
+	for entry, state := range s.entries {
+		*entry = state
+		entry.StartNewLines = slices.Clone(state.StartNewLines)
+		entry.EndNewLines = slices.Clone(state.EndNewLines)
+	}
+
+	for comment, state := range s.comments {
+		*comment = state
+	}
+
+	for directive, state := range s.directives {
+		*directive = state
+		directive.Values = slices.Clone(state.Values)
+	}
+
+	for block, state := range s.blocks {
+		*block = state
+		block.Parameters = slices.Clone(state.Parameters)
+	}
+
+	for content, state := range s.contents {
+		*content = state
+		content.Entries = slices.Clone(state.Entries)
+	}
+
+	for value, state := range s.values {
+		*value = state
+	}
+}
+
+func (s *Snapshot) addEntries(entries []*Entry) {
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+
+		s.entries[entry] = Entry{
+			StartNewLines:  slices.Clone(entry.StartNewLines),
+			Comment:        entry.Comment,
+			Directive:      entry.Directive,
+			BlockDirective: entry.BlockDirective,
+			EndNewLines:    slices.Clone(entry.EndNewLines),
+		}
+
+		if entry.Comment != nil {
+			s.comments[entry.Comment] = *entry.Comment
+		}
+
+		if entry.Directive != nil {
+			s.directives[entry.Directive] = Directive{
+				Pos:        entry.Directive.Pos,
+				Identifier: entry.Directive.Identifier,
+				Values:     slices.Clone(entry.Directive.Values),
+			}
+			s.addValues(entry.Directive.Values)
+		}
+
+		if entry.BlockDirective != nil {
+			s.addBlock(entry.BlockDirective)
+		}
+	}
+}
+
+func (s *Snapshot) addBlock(block *BlockDirective) {
+	s.blocks[block] = BlockDirective{
+		Pos:        block.Pos,
+		Identifier: block.Identifier,
+		Parameters: slices.Clone(block.Parameters),
+		Content:    block.Content,
+	}
+	s.addValues(block.Parameters)
+
+	if block.Content != nil {
+		s.contents[block.Content] = BlockContent{Entries: slices.Clone(block.Content.Entries)}
+		s.addEntries(block.Content.Entries)
+	}
+}
+
+func (s *Snapshot) addValues(values []*Value) {
+	for _, value := range values {
+		if value != nil {
+			s.values[value] = *value
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package unifieddiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultContextLines = 3
+	noNewLineMarker     = "\\ No newline at end of file\n"
+)
+
+type operationKind int
+
+const (
+	equal operationKind = iota
+	deletion
+	insertion
+)
+
+type operation struct {
+	kind operationKind
+	// line indexes in the old and the new content
+	oldIndex int
+	newIndex int
+}
+
+// Diff returns unified diff between two texts, empty string is returned if texts are equal
+func Diff(oldName, newName, oldContent, newContent string, contextLines int) string {
+	if oldContent == newContent {
+		return ""
+	}
+
+	oldLines := splitLines(oldContent)
+	newLines := splitLines(newContent)
+	operations := getOperations(oldLines, newLines)
+
+	var result strings.Builder
+
+	result.WriteString("--- " + oldName + "\n")
+	result.WriteString("+++ " + newName + "\n")
+
+	for _, hunk := range getHunks(operations, contextLines) {
+		writeHunk(&result, hunk, oldLines, newLines)
+	}
+
+	return result.String()
+}
+
+// splitLines keeps line endings, so a missing new line at the end of the file is detected
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// getOperations builds the shortest edit script with the linear space variant of the Myers algorithm:
+// sequences are split at the middle snake of the edit path and both halves are compared recursively
+func getOperations(oldLines, newLines []string) []operation {
+	ids := make(map[string]int)
+	differ := &differ{
+		oldIds:     getLineIds(oldLines, ids),
+		newIds:     getLineIds(newLines, ids),
+		operations: make([]operation, 0, len(oldLines)+len(newLines)),
+	}
+	differ.compare(0, len(oldLines), 0, len(newLines))
+
+	return differ.operations
+}
+
+// getLineIds replaces lines with numbers, so that lines are compared once
+func getLineIds(lines []string, ids map[string]int) []int {
+	lineIds := make([]int, len(lines))
+
+	for index, line := range lines {
+		id, ok := ids[line]
+
+		if !ok {
+			id = len(ids)
+			ids[line] = id
+		}
+
+		lineIds[index] = id
+	}
+
+	return lineIds
+}
+
+type differ struct {
+	oldIds     []int
+	newIds     []int
+	operations []operation
+}
+
+// compare appends operations turning old[oldStart:oldEnd] into new[newStart:newEnd]
+func (d *differ) compare(oldStart, oldEnd, newStart, newEnd int) {
+	prefixEnd := oldStart
+
+	for prefixEnd < oldEnd && newStart+prefixEnd-oldStart < newEnd && d.oldIds[prefixEnd] == d.newIds[newStart+prefixEnd-oldStart] {
+		prefixEnd++
+	}
+
+	d.addEqual(oldStart, newStart, prefixEnd-oldStart)
+	newStart += prefixEnd - oldStart
+	oldStart = prefixEnd
+
+	suffixLength := 0
+
+	for oldEnd-suffixLength > oldStart && newEnd-suffixLength > newStart && d.oldIds[oldEnd-suffixLength-1] == d.newIds[newEnd-suffixLength-1] {
+		suffixLength++
+	}
+
+	oldEnd -= suffixLength
+	newEnd -= suffixLength
+
+	switch {
+	case oldStart == oldEnd:
+		for index := newStart; index < newEnd; index++ {
+			d.operations = append(d.operations, operation{kind: insertion, oldIndex: oldStart, newIndex: index})
+		}
+	case newStart == newEnd:
+		for index := oldStart; index < oldEnd; index++ {
+			d.operations = append(d.operations, operation{kind: deletion, oldIndex: index, newIndex: newStart})
+		}
+	default:
+		if oldSplit, newSplit, ok := d.findMiddleSnake(oldStart, oldEnd, newStart, newEnd); ok {
+			d.compare(oldStart, oldSplit, newStart, newSplit)
+			d.compare(oldSplit, oldEnd, newSplit, newEnd)
+		} else {
+			// nothing is common, the old lines are replaced
+			d.compare(oldStart, oldEnd, newStart, newStart)
+			d.compare(oldEnd, oldEnd, newStart, newEnd)
+		}
+	}
+
+	d.addEqual(oldEnd, newEnd, suffixLength)
+}
+
+func (d *differ) addEqual(oldStart, newStart, length int) {
+	for index := 0; index < length; index++ {
+		d.operations = append(d.operations, operation{kind: equal, oldIndex: oldStart + index, newIndex: newStart + index})
+	}
+}
+
+// findMiddleSnake searches the edit path from both ends at once and returns the point where the paths meet.
+// Only the furthest points of the current step are kept, so memory is linear in the length of the content.
+func (d *differ) findMiddleSnake(oldStart, oldEnd, newStart, newEnd int) (int, int, bool) {
+	oldIds, newIds := d.oldIds[oldStart:oldEnd], d.newIds[newStart:newEnd]
+	n, m := len(oldIds), len(newIds)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+
+	for index := range forward {
+		forward[index] = -1
+		backward[index] = -1
+	}
+
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	delta := n - m
+	// the paths meet while extending the forward one if the difference of lengths is odd
+	checkForward := delta%2 != 0
+	// diagonals leaving the edit graph are skipped
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for step := 0; step < maxD; step++ {
+		for k := -step + forwardStart; k <= step-forwardEnd; k += 2 {
+			var x int
+
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && oldIds[x] == newIds[y] {
+				x++
+				y++
+			}
+
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case checkForward:
+				backwardIndex := offset + delta - k
+
+				if backwardIndex >= 0 && backwardIndex < len(backward) && backward[backwardIndex] != -1 && x >= n-backward[backwardIndex] {
+					return oldStart + x, newStart + y, true
+				}
+			}
+		}
+
+		for k := -step + backwardStart; k <= step-backwardEnd; k += 2 {
+			var x int
+
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && oldIds[n-x-1] == newIds[m-y-1] {
+				x++
+				y++
+			}
+
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !checkForward:
+				forwardIndex := offset + delta - k
+
+				if forwardIndex >= 0 && forwardIndex < len(forward) && forward[forwardIndex] != -1 {
+					forwardX := forward[forwardIndex]
+					forwardY := forwardX - (forwardIndex - offset)
+
+					if forwardX >= n-x {
+						return oldStart + forwardX, newStart + forwardY, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+func getHunks(operations []operation, contextLines int) [][]operation {
+	var hunks [][]operation
+	start, end := -1, -1
+
+	for index, op := range operations {
+		if op.kind == equal {
+			continue
+		}
+
+		hunkStart := max(index-contextLines, 0)
+
+		if start != -1 && hunkStart > end {
+			hunks = append(hunks, operations[start:end])
+			start = -1
+		}
+
+		if start == -1 {
+			start = hunkStart
+		}
+
+		end = min(index+contextLines+1, len(operations))
+	}
+
+	if start != -1 {
+		hunks = append(hunks, operations[start:end])
+	}
+
+	return hunks
+}
+
+func writeHunk(result *strings.Builder, hunk []operation, oldLines, newLines []string) {
+	oldStart, newStart := hunk[0].oldIndex, hunk[0].newIndex
+	oldCount, newCount := 0, 0
+
+	for _, op := range hunk {
+		if op.kind != insertion {
+			oldCount++
+		}
+
+		if op.kind != deletion {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(result, "@@ -%s +%s @@\n", getRange(oldStart, oldCount), getRange(newStart, newCount))
+
+	for _, op := range hunk {
+		switch op.kind {
+		case equal:
+			writeLine(result, " ", oldLines[op.oldIndex])
+		case deletion:
+			writeLine(result, "-", oldLines[op.oldIndex])
+		case insertion:
+			writeLine(result, "+", newLines[op.newIndex])
+		}
+	}
+}
+
+func writeLine(result *strings.Builder, prefix, line string) {
+	result.WriteString(prefix + line)
+
+	if !strings.HasSuffix(line, "\n") {
+		result.WriteString("\n" + noNewLineMarker)
+	}
+}
+
+func getRange(start, count int) string {
+	// an empty range refers to the line before it
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprint(start + 1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
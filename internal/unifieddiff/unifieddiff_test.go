@@ -0,0 +1,62 @@
+package unifieddiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	oldContent := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	newContent := "a\nB\nc\nd\ne\nf\ng\nh\ni\nk\nl"
+
+	expectedDiff := `--- a/file
++++ b/file
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -7,5 +7,5 @@
+ g
+ h
+ i
+-j
+ k
++l
+\ No newline at end of file
+`
+	assert.Equal(t, expectedDiff, Diff("a/file", "b/file", oldContent, newContent, DefaultContextLines))
+	assert.Empty(t, Diff("a/file", "b/file", oldContent, oldContent, DefaultContextLines))
+}
+
+func TestDiffNewAndEmptiedFile(t *testing.T) {
+	expectedDiff := "--- /dev/null\n+++ b/file\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+	assert.Equal(t, expectedDiff, Diff("/dev/null", "b/file", "", "a\nb\n", DefaultContextLines))
+
+	expectedDiff = "--- a/file\n+++ b/file\n@@ -1 +0,0 @@\n-a\n"
+	assert.Equal(t, expectedDiff, Diff("a/file", "b/file", "a\n", "", DefaultContextLines))
+}
+
+func TestDiffRewrittenFile(t *testing.T) {
+	var oldContent, newContent strings.Builder
+
+	for index := 0; index < 4000; index++ {
+		fmt.Fprintf(&oldContent, "old %d;\n", index)
+		fmt.Fprintf(&newContent, "new %d;\n", index)
+	}
+
+	diff := Diff("a/file", "b/file", oldContent.String(), newContent.String(), DefaultContextLines)
+	assert.True(t, strings.HasPrefix(diff, "--- a/file\n+++ b/file\n@@ -1,4000 +1,4000 @@\n-old 0;\n"))
+	assert.Equal(t, 4000, strings.Count(diff, "\n-old "))
+	assert.Equal(t, 4000, strings.Count(diff, "\n+new "))
+}
+
+func TestDiffMovedLines(t *testing.T) {
+	expectedDiff := "--- a/file\n+++ b/file\n@@ -1,3 +1,3 @@\n-a\n b\n c\n+a\n"
+	assert.Equal(t, expectedDiff, Diff("a/file", "b/file", "a\nb\nc\n", "b\nc\na\n", DefaultContextLines))
+}